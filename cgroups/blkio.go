@@ -0,0 +1,205 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IODevice holds the IO counters of the cgroup for a single block device.
+type IODevice struct {
+	// Device is the `major:minor` number of the block device.
+	Device     string `json:"device"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	ReadIOs    uint64 `json:"read_ios"`
+	WriteIOs   uint64 `json:"write_ios"`
+}
+
+// IO describes the IO controller statistics of the cgroup.
+type IO struct {
+	Devices []IODevice `json:"devices,omitempty"`
+	// Pressure is the IO pressure stall information, only available on v2
+	// when PSI is enabled.
+	Pressure *Pressure `json:"pressure,omitempty"`
+}
+
+// PressureStat is one line of a pressure stall information file.
+type PressureStat struct {
+	Avg10  float64       `json:"avg10"`
+	Avg60  float64       `json:"avg60"`
+	Avg300 float64       `json:"avg300"`
+	Total  time.Duration `json:"total"`
+}
+
+// Pressure is the pressure stall information of a resource: the share of
+// time some or all tasks were stalled waiting for it.
+// https://www.kernel.org/doc/html/latest/accounting/psi.html
+type Pressure struct {
+	Some PressureStat `json:"some"`
+	Full PressureStat `json:"full"`
+}
+
+// parseIOStat parses the content of the v2 io.stat file, where each line
+// holds the nested keys of a device, e.g.
+// `8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0`.
+func parseIOStat(content string) ([]IODevice, error) {
+	var devices []IODevice
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		d := IODevice{Device: fields[0]}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid io.stat entry: %q", scanner.Text())
+			}
+
+			var dst *uint64
+			switch key {
+			case "rbytes":
+				dst = &d.ReadBytes
+			case "wbytes":
+				dst = &d.WriteBytes
+			case "rios":
+				dst = &d.ReadIOs
+			case "wios":
+				dst = &d.WriteIOs
+			default:
+				continue
+			}
+
+			v, err := parseUint(value)
+			if err != nil {
+				return nil, err
+			}
+			*dst = v
+		}
+
+		devices = append(devices, d)
+	}
+
+	return devices, scanner.Err()
+}
+
+// parseBlkioStat parses the content of the v1 blkio.throttle.io_service_bytes
+// or blkio.throttle.io_serviced files into the read and write counters of
+// devices, selected by bytes. Each line is in the format `8:0 Read 4096` and
+// the content ends with the `Total` of all devices.
+func parseBlkioStat(content string, devices map[string]*IODevice, bytes bool) error {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			// Skip the trailing `Total <value>` line.
+			continue
+		}
+
+		d, exists := devices[fields[0]]
+		if !exists {
+			d = &IODevice{Device: fields[0]}
+			devices[fields[0]] = d
+		}
+
+		var dst *uint64
+		switch {
+		case fields[1] == "Read" && bytes:
+			dst = &d.ReadBytes
+		case fields[1] == "Write" && bytes:
+			dst = &d.WriteBytes
+		case fields[1] == "Read":
+			dst = &d.ReadIOs
+		case fields[1] == "Write":
+			dst = &d.WriteIOs
+		default:
+			continue
+		}
+
+		v, err := parseUint(fields[2])
+		if err != nil {
+			return err
+		}
+		*dst = v
+	}
+
+	return scanner.Err()
+}
+
+// sortedDevices returns the devices sorted by their `major:minor` string.
+func sortedDevices(devices map[string]*IODevice) []IODevice {
+	out := make([]IODevice, 0, len(devices))
+	for _, d := range devices {
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Device < out[j].Device
+	})
+	return out
+}
+
+// parsePressure parses the content of a pressure stall information file:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//
+// total is expressed in microseconds.
+func parsePressure(content string) (Pressure, error) {
+	var p Pressure
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var stat *PressureStat
+		switch fields[0] {
+		case "some":
+			stat = &p.Some
+		case "full":
+			stat = &p.Full
+		default:
+			return Pressure{}, fmt.Errorf("invalid pressure entry: %q", scanner.Text())
+		}
+
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return Pressure{}, fmt.Errorf("invalid pressure entry: %q", scanner.Text())
+			}
+
+			if key == "total" {
+				v, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return Pressure{}, err
+				}
+				stat.Total = time.Duration(v) * time.Microsecond
+				continue
+			}
+
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return Pressure{}, err
+			}
+			switch key {
+			case "avg10":
+				stat.Avg10 = v
+			case "avg60":
+				stat.Avg60 = v
+			case "avg300":
+				stat.Avg300 = v
+			}
+		}
+	}
+
+	return p, scanner.Err()
+}
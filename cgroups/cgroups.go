@@ -4,42 +4,94 @@
 package cgroups
 
 import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
 	"sync"
 
 	"golang.org/x/sys/unix"
 )
 
 const (
-	cgroupMountPoint  = "/sys/fs/cgroup"
-	procCGroupPath    = "/proc/self/cgroup"
-	procMountInfoPath = "/proc/self/mountinfo"
+	cgroupMountPoint = "/sys/fs/cgroup"
+	procCGroupNSPath = "/proc/self/ns/cgroup"
+
+	// initCGroupNSInode is the inode of the initial cgroup namespace,
+	// PROC_CGROUP_INIT_INO in the kernel.
+	initCGroupNSInode = 0xEFFFFFFB
 )
 
 type cgroup interface {
-	cpuQuota() (float64, error)
+	version() int
+	path() string
+	name() string
+	cpuQuota() (float64, string, error)
+	cpuBurst(dir string) (float64, error)
+	cpuWeight() (CPUWeight, error)
+	schedAttrs() (*SchedAttrs, error)
 	cpuUsage() (uint64, error)
+	cpuset() (string, error)
 	effectiveCPUs() (int, error)
+	throttling() (Throttling, error)
+	cpuCounters() (uint64, Throttling, error)
+	memory() (Memory, error)
+	blockIO() (IO, error)
+	pids() (PIDs, error)
+	controllers() []string
+	cpuFiles() []string
+}
+
+// procPaths are the files the cgroup of the current process is detected
+// from: the mount point of the cgroup filesystem, /proc/self/cgroup and
+// /proc/self/mountinfo.
+type procPaths struct {
+	mountPoint string
+	cgroup     string
+	mountInfo  string
+}
+
+var selfPaths = procPaths{
+	mountPoint: cgroupMountPoint,
+	cgroup:     procCGroupPath,
+	mountInfo:  procMountInfoPath,
 }
 
 var (
 	checkMode sync.Once
 	isUnified bool
+	modeErr   error
 )
 
-func newCGroup() (cgroup, error) {
-	if isUnifiedMode() {
-		return newCGroupV2()
+// newCGroup returns the cgroup of the current process, logging the detection
+// decisions to log.
+func newCGroup(log *slog.Logger) (cgroup, error) {
+	unified := isUnifiedMode()
+	if modeErr != nil {
+		log.Debug("cgroup: statfs failed, assuming cgroup v1",
+			"mount", cgroupMountPoint, "error", modeErr)
+	}
+
+	return newCGroupFrom(log, unified, selfPaths)
+}
+
+// newCGroupFrom returns the cgroup described by the files of p, in the
+// unified or legacy hierarchy.
+func newCGroupFrom(log *slog.Logger, unified bool, p procPaths) (cgroup, error) {
+	if unified {
+		log.Debug("cgroup: unified hierarchy detected", "mount", p.mountPoint)
+		return newCGroupV2(log, p)
 	}
 
-	return newCGroupV1()
+	log.Debug("cgroup: legacy hierarchy detected", "mount", p.mountPoint)
+	return newCGroupV1(log, p)
 }
 
 // mode returns the cgroups mode running on the host
 func isUnifiedMode() bool {
 	checkMode.Do(func() {
 		var st unix.Statfs_t
-		err := unix.Statfs(cgroupMountPoint, &st)
-		if err != nil {
+		if modeErr = unix.Statfs(cgroupMountPoint, &st); modeErr != nil {
 			return
 		}
 		isUnified = st.Type == unix.CGROUP2_SUPER_MAGIC
@@ -47,3 +99,17 @@ func isUnifiedMode() bool {
 
 	return isUnified
 }
+
+// cgroupNamespace returns the inode of the cgroup namespace of the current
+// process, or 0 if the kernel does not support cgroup namespaces (< 4.6).
+func cgroupNamespace() (uint64, error) {
+	link, err := os.Readlink(procCGroupNSPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return parseNamespaceLink(link)
+}
@@ -4,33 +4,597 @@
 package cgroups
 
 import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// writeProcFixture writes the /proc/self/cgroup and mountinfo files of a
+// host whose cgroup filesystem is mounted at the fs directory of a temporary
+// directory, with the cgroup files below it. mountInfo is a format string
+// whose first argument is the fs directory.
+func writeProcFixture(t *testing.T, cgroup, mountInfo string, files map[string]string) procPaths {
+	t.Helper()
+
+	root := t.TempDir()
+	p := procPaths{
+		mountPoint: filepath.Join(root, "fs"),
+		cgroup:     filepath.Join(root, "cgroup"),
+		mountInfo:  filepath.Join(root, "mountinfo"),
+	}
+
+	require.NoError(t, os.WriteFile(p.cgroup, []byte(cgroup), 0o644))
+	require.NoError(t, os.WriteFile(p.mountInfo, []byte(fmt.Sprintf(mountInfo, p.mountPoint)), 0o644))
+	for name, content := range files {
+		file := filepath.Join(p.mountPoint, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	}
+
+	return p
+}
+
 func TestCgroups(t *testing.T) {
 	// test cgroup legacy(v1) & hybrid
 	if !isUnifiedMode() {
-		cg, err := newCGroupV1()
+		cg, err := newCGroupV1(discardLogger, selfPaths)
 		assert.NoError(t, err)
 		_, err = cg.effectiveCPUs()
 		assert.NoError(t, err)
-		_, err = cg.cpuQuota()
+		_, _, err = cg.cpuQuota()
 		assert.NoError(t, err)
 		_, err = cg.cpuUsage()
 		assert.NoError(t, err)
+		_, err = cg.throttling()
+		assert.NoError(t, err)
+		_, err = cg.memory()
+		assert.NoError(t, err)
+		_, err = cg.blockIO()
+		assert.NoError(t, err)
+		_, err = cg.pids()
+		assert.NoError(t, err)
 	}
 
 	// test cgroup v2
 	if isUnifiedMode() {
-		cg, err := newCGroupV2()
+		cg, err := newCGroupV2(discardLogger, selfPaths)
 		assert.NoError(t, err)
 		_, err = cg.effectiveCPUs()
 		assert.NoError(t, err)
-		_, err = cg.cpuQuota()
+		_, _, err = cg.cpuQuota()
 		assert.NoError(t, err)
 		_, err = cg.cpuUsage()
 		assert.NoError(t, err)
+		_, err = cg.throttling()
+		assert.NoError(t, err)
+		_, err = cg.memory()
+		assert.NoError(t, err)
+		_, err = cg.blockIO()
+		assert.NoError(t, err)
+		_, err = cg.pids()
+		assert.NoError(t, err)
+	}
+}
+
+func TestCGroupV1Memory(t *testing.T) {
+	cg := &cgroupv1{
+		cgroups: map[string]string{
+			"memory": filepath.Join(testDataCGroupsPath, "v1", "memory"),
+		},
+	}
+
+	m, err := cg.memory()
+	assert.NoError(t, err)
+	assert.Equal(t, Memory{
+		Usage:   104857600,
+		Limit:   0,
+		Stat:    map[string]uint64{"cache": 4096, "rss": 100000000},
+		OOMKill: 2,
+	}, m)
+
+	m, err = (&cgroupv1{}).memory()
+	assert.NoError(t, err)
+	assert.Equal(t, Memory{}, m)
+}
+
+func TestCGroupV2Memory(t *testing.T) {
+	cg := &cgroupv2{dir: filepath.Join(testDataCGroupsPath, "v2")}
+
+	m, err := cg.memory()
+	assert.NoError(t, err)
+	assert.Equal(t, Memory{
+		Usage:   52428800,
+		Limit:   268435456,
+		Stat:    map[string]uint64{"anon": 40000000, "file": 12000000},
+		OOM:     3,
+		OOMKill: 1,
+	}, m)
+
+	m, err = (&cgroupv2{dir: filepath.Join(testDataCGroupsPath, "empty")}).memory()
+	assert.NoError(t, err)
+	assert.Equal(t, Memory{}, m)
+}
+
+func TestCGroupV1BlockIO(t *testing.T) {
+	cg := &cgroupv1{
+		cgroups: map[string]string{
+			"blkio": filepath.Join(testDataCGroupsPath, "v1", "blkio"),
+		},
+	}
+
+	blkio, err := cg.blockIO()
+	assert.NoError(t, err)
+	assert.Equal(t, IO{
+		Devices: []IODevice{
+			{Device: "259:0", ReadBytes: 4096, WriteBytes: 0, ReadIOs: 1, WriteIOs: 0},
+			{Device: "8:0", ReadBytes: 1048576, WriteBytes: 524288, ReadIOs: 16, WriteIOs: 8},
+		},
+	}, blkio)
+}
+
+func TestCGroupV2BlockIO(t *testing.T) {
+	cg := &cgroupv2{dir: filepath.Join(testDataCGroupsPath, "v2")}
+
+	blkio, err := cg.blockIO()
+	assert.NoError(t, err)
+	assert.Equal(t, IO{
+		Devices: []IODevice{
+			{Device: "8:16", ReadBytes: 1459200, WriteBytes: 314773504, ReadIOs: 192, WriteIOs: 353},
+		},
+		Pressure: &Pressure{
+			Some: PressureStat{Avg10: 1.5, Avg60: 0.75, Avg300: 0.25, Total: 42 * time.Millisecond},
+			Full: PressureStat{Avg10: 0.5, Total: 12 * time.Millisecond},
+		},
+	}, blkio)
+
+	// With psi=0 before Linux 5.17, io.pressure exists but reading it fails.
+	// A directory fails the same way.
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "io.pressure"), 0o755))
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	blkio, err = (&cgroupv2{dir: dir, log: log}).blockIO()
+	assert.NoError(t, err)
+	assert.Equal(t, IO{}, blkio)
+	assert.Contains(t, buf.String(), `msg="cgroup: io.pressure unavailable"`)
+}
+
+func TestCGroupPIDs(t *testing.T) {
+	p, err := (&cgroupv1{
+		cgroups: map[string]string{
+			"pids": filepath.Join(testDataCGroupsPath, "v1", "pids"),
+		},
+	}).pids()
+	assert.NoError(t, err)
+	assert.Equal(t, PIDs{Current: 42}, p)
+
+	p, err = (&cgroupv2{dir: filepath.Join(testDataCGroupsPath, "v2")}).pids()
+	assert.NoError(t, err)
+	assert.Equal(t, PIDs{Current: 12, Limit: 100}, p)
+	assert.InDelta(t, 12.0, p.Percent(), 1e-9)
+
+	p, err = (&cgroupv2{dir: filepath.Join(testDataCGroupsPath, "empty")}).pids()
+	assert.NoError(t, err)
+	assert.Equal(t, PIDs{}, p)
+}
+
+func TestCGroupCPUWeight(t *testing.T) {
+	weightPath := filepath.Join(testDataCGroupsPath, "weight")
+
+	w, err := (&cgroupv2{dir: filepath.Join(weightPath, "v2", "pod", "a")}).cpuWeight()
+	assert.NoError(t, err)
+	assert.Equal(t, CPUWeight{Weight: 200, Nice: -3, SiblingsTotal: 300}, w)
+	assert.InDelta(t, 4.0, w.guaranteedCores(6), 1e-9)
+
+	w, err = (&cgroupv2{dir: filepath.Join(weightPath, "v2", "pod")}).cpuWeight()
+	assert.NoError(t, err)
+	assert.Equal(t, CPUWeight{Weight: 100}, w)
+	assert.Zero(t, w.guaranteedCores(6))
+
+	w, err = (&cgroupv1{
+		cgroups: map[string]string{
+			"cpu": filepath.Join(weightPath, "v1", "cpu", "a"),
+		},
+	}).cpuWeight()
+	assert.NoError(t, err)
+	assert.Equal(t, CPUWeight{Shares: 512, SiblingsTotal: 2048}, w)
+	assert.InDelta(t, 1.0, w.guaranteedCores(4), 1e-9)
+}
+
+func TestCGroupCPUBurst(t *testing.T) {
+	dir := filepath.Join(testDataCGroupsPath, "v2")
+	burst, err := (&cgroupv2{dir: dir}).cpuBurst(dir)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, burst, 1e-9)
+
+	dir = filepath.Join(testDataCGroupsPath, "cpu")
+	burst, err = (&cgroupv1{
+		cgroups: map[string]string{"cpu": dir},
+	}).cpuBurst(dir)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2.0, burst, 1e-9)
+
+	dir = filepath.Join(testDataCGroupsPath, "empty")
+	burst, err = (&cgroupv2{dir: dir}).cpuBurst(dir)
+	assert.NoError(t, err)
+	assert.Zero(t, burst)
+}
+
+func TestCGroupBurstOfQuotaPath(t *testing.T) {
+	// The quota of the pod binds. The container burst, with its own period,
+	// would amount to 2 cores.
+	p := writeProcFixture(t, "0::/pod/container\n",
+		"30 22 0:26 / %[1]s rw,relatime - cgroup2 cgroup2 rw\n",
+		map[string]string{
+			"pod/cpu.max":                         "100000 100000",
+			"pod/cpu.max.burst":                   "50000",
+			"pod/container/cpu.max":               "200000 50000",
+			"pod/container/cpu.max.burst":         "100000",
+			"pod/container/cpu.stat":              "usage_usec 100",
+			"pod/container/cpuset.cpus.effective": "0-3",
+		})
+
+	cg, err := newCGroupFrom(discardLogger, true, p)
+	require.NoError(t, err)
+
+	info, err := cgroupSource{cg: cg, log: discardLogger}.info()
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, info.Quota, 1e-9)
+	assert.Equal(t, filepath.Join(p.mountPoint, "pod"), info.QuotaPath)
+	assert.InDelta(t, 0.5, info.Burst, 1e-9)
+}
+
+func TestParseThrottling(t *testing.T) {
+	stats := map[string]string{
+		"usage_usec":     "20905476302",
+		"nr_periods":     "100",
+		"nr_throttled":   "10",
+		"throttled_usec": "5000",
+		"nr_bursts":      "3",
+		"burst_usec":     "2000",
+	}
+
+	th, err := parseThrottling(stats, "_usec", time.Microsecond)
+	assert.NoError(t, err)
+	assert.Equal(t, Throttling{
+		Periods:          100,
+		ThrottledPeriods: 10,
+		ThrottledTime:    5 * time.Millisecond,
+		Bursts:           3,
+		BurstTime:        2 * time.Millisecond,
+	}, th)
+
+	_, err = parseThrottling(map[string]string{"nr_periods": "x"}, "_usec", time.Microsecond)
+	assert.Error(t, err)
+}
+
+func TestCGroupSchedAttrs(t *testing.T) {
+	attrs, err := (&cgroupv2{dir: filepath.Join(testDataCGroupsPath, "v2")}).schedAttrs()
+	assert.NoError(t, err)
+	assert.Equal(t, &SchedAttrs{UclampMin: 12.5, UclampMax: 100, Idle: true}, attrs)
+
+	attrs, err = (&cgroupv2{dir: filepath.Join(testDataCGroupsPath, "empty")}).schedAttrs()
+	assert.NoError(t, err)
+	assert.Nil(t, attrs)
+
+	attrs, err = (&cgroupv1{}).schedAttrs()
+	assert.NoError(t, err)
+	assert.Nil(t, attrs)
+}
+
+func TestCGroupHierarchicalQuota(t *testing.T) {
+	quotaPath := filepath.Join(testDataCGroupsPath, "quota")
+
+	testTable := []struct {
+		name            string
+		cg              cgroup
+		expectedQuota   float64
+		expectedBinding string
+	}{
+		{
+			name:            "v2-ancestor",
+			cg:              &cgroupv2{dir: filepath.Join(quotaPath, "v2", "kubepods", "pod", "container"), mount: filepath.Join(quotaPath, "v2")},
+			expectedQuota:   0.5,
+			expectedBinding: filepath.Join(quotaPath, "v2", "kubepods", "pod"),
+		},
+		{
+			name:            "v2-leaf",
+			cg:              &cgroupv2{dir: filepath.Join(quotaPath, "v2", "kubepods", "pod", "sidecar"), mount: filepath.Join(quotaPath, "v2")},
+			expectedQuota:   0.25,
+			expectedBinding: filepath.Join(quotaPath, "v2", "kubepods", "pod", "sidecar"),
+		},
+		{
+			name: "v1-ancestor",
+			cg: &cgroupv1{
+				cgroups: map[string]string{"cpu": filepath.Join(quotaPath, "v1", "pod", "container")},
+				mounts:  map[string]string{"cpu": filepath.Join(quotaPath, "v1")},
+			},
+			expectedQuota:   1.5,
+			expectedBinding: filepath.Join(quotaPath, "v1", "pod"),
+		},
+	}
+
+	for _, tt := range testTable {
+		quota, binding, err := tt.cg.cpuQuota()
+		assert.NoError(t, err, tt.name)
+		assert.InDelta(t, tt.expectedQuota, quota, 1e-9, tt.name)
+		assert.Equal(t, tt.expectedBinding, binding, tt.name)
+	}
+}
+
+func TestCGroupV1QuotaFromMountInfo(t *testing.T) {
+	p := writeProcFixture(t, "4:cpu,cpuacct:/kubepods/pod/container\n",
+		"33 22 0:29 / %[1]s/cpu,cpuacct rw,relatime - cgroup cgroup rw,cpu,cpuacct\n",
+		map[string]string{
+			"cpu,cpuacct/cpu.cfs_quota_us":                         "-1",
+			"cpu,cpuacct/kubepods/cpu.cfs_quota_us":                "-1",
+			"cpu,cpuacct/kubepods/pod/cpu.cfs_quota_us":            "100000",
+			"cpu,cpuacct/kubepods/pod/cpu.cfs_period_us":           "100000",
+			"cpu,cpuacct/kubepods/pod/container/cpu.cfs_quota_us":  "200000",
+			"cpu,cpuacct/kubepods/pod/container/cpu.cfs_period_us": "100000",
+		})
+
+	cg, err := newCGroupV1(discardLogger, p)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(p.mountPoint, "cpu,cpuacct", "kubepods", "pod", "container"), cg.path())
+
+	quota, binding, err := cg.cpuQuota()
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, quota, 1e-9)
+	assert.Equal(t, filepath.Join(p.mountPoint, "cpu,cpuacct", "kubepods", "pod"), binding)
+}
+
+func TestKubeResources(t *testing.T) {
+	mount := filepath.Join(testDataCGroupsPath, "kube")
+	podName := "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234_abcd.slice"
+	name := podName + "/cri-containerd-abc.scope"
+	podPath := filepath.Join(mount, podName)
+	containerPath := filepath.Join(mount, name)
+
+	pod := KubeCPU{Path: podPath, Quota: 1.5, Weight: CPUWeight{Weight: 20, SiblingsTotal: 20}, RequestMillis: 501, LimitMillis: 1500}
+	container := KubeCPU{Path: containerPath, Quota: 1, Weight: CPUWeight{Weight: 20, SiblingsTotal: 20}, RequestMillis: 501, LimitMillis: 1000}
+
+	tests := []struct {
+		name              string
+		cg                cgroup
+		expectedPod       KubeCPU
+		expectedContainer KubeCPU
+	}{
+		{
+			name:              "host cgroup namespace",
+			cg:                &cgroupv2{dir: containerPath, mount: mount, cgroupName: name},
+			expectedPod:       pod,
+			expectedContainer: container,
+		},
+		{
+			name:              "pod mounted in the container",
+			cg:                &cgroupv2{dir: containerPath, mount: podPath, cgroupName: name},
+			expectedPod:       pod,
+			expectedContainer: container,
+		},
+		{
+			name:              "container mounted in the container",
+			cg:                &cgroupv2{dir: containerPath, mount: containerPath, cgroupName: name},
+			expectedContainer: container,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := kubeResources(tt.cg)
+			require.NoError(t, err)
+			assert.Equal(t, QoSBurstable, res.QoS)
+			assert.Equal(t, "1234-abcd", res.PodUID)
+			assert.Equal(t, tt.expectedPod, res.Pod)
+			assert.Equal(t, tt.expectedContainer, res.Container)
+		})
+	}
+
+	_, err := kubeResources(&cgroupv2{dir: containerPath, mount: mount, cgroupName: "/system.slice/docker-abc.scope"})
+	assert.ErrorIs(t, err, ErrNotKubernetes)
+}
+
+func TestKubeResourcesV1Container(t *testing.T) {
+	name := "/kubepods/burstable/pod1234-abcd/abc"
+	p := writeProcFixture(t, "4:cpu,cpuacct:"+name+"\n",
+		"33 22 0:29 "+name+" %[1]s/cpu,cpuacct ro,relatime - cgroup cgroup rw,cpu,cpuacct\n",
+		map[string]string{
+			"cpu,cpuacct/cpu.cfs_quota_us":  "50000",
+			"cpu,cpuacct/cpu.cfs_period_us": "100000",
+			"cpu,cpuacct/cpu.shares":        "512",
+		})
+
+	cg, err := newCGroupFrom(discardLogger, false, p)
+	require.NoError(t, err)
+
+	res, err := kubeResources(cg)
+	require.NoError(t, err)
+	assert.Equal(t, QoSBurstable, res.QoS)
+	assert.Equal(t, "1234-abcd", res.PodUID)
+	assert.Equal(t, KubeCPU{}, res.Pod, "the pod cgroup is not visible")
+	assert.Equal(t, KubeCPU{
+		Path:          filepath.Join(p.mountPoint, "cpu,cpuacct"),
+		Quota:         0.5,
+		Weight:        CPUWeight{Shares: 512},
+		RequestMillis: 500,
+		LimitMillis:   500,
+	}, res.Container)
+}
+
+func TestInfoContainerIDs(t *testing.T) {
+	id := "1753b7cbbf62734d812936961224d5bc0cf8f45214e0d5cdd1a781a053e7c48f"
+	kubePath := "/kubepods/burstable/pod1234-abcd/" + id
+
+	tests := []struct {
+		name          string
+		unified       bool
+		cgroup        string
+		mountInfo     string
+		files         map[string]string
+		expectedPath  string
+		expectedID    string
+		expectedPodID string
+	}{
+		{
+			name:   "v1 container with the host cgroup namespace",
+			cgroup: "4:cpu,cpuacct:/docker/" + id + "\n3:cpuset:/docker/" + id + "\n",
+			mountInfo: "33 22 0:29 /docker/" + id + " %[1]s/cpu,cpuacct ro,relatime - cgroup cgroup rw,cpu,cpuacct\n" +
+				"34 22 0:30 /docker/" + id + " %[1]s/cpuset ro,relatime - cgroup cgroup rw,cpuset\n",
+			files: map[string]string{
+				"cpu,cpuacct/cpu.cfs_quota_us": "-1",
+				"cpuset/cpuset.cpus":           "0-1",
+			},
+			expectedPath: "cpu,cpuacct",
+			expectedID:   id,
+		},
+		{
+			name:      "v2 pod with the host cgroup namespace",
+			unified:   true,
+			cgroup:    "0::" + kubePath + "\n",
+			mountInfo: "30 22 0:26 " + kubePath + " %[1]s ro,relatime - cgroup2 cgroup2 rw\n",
+			files: map[string]string{
+				"cpu.stat":              "usage_usec 100",
+				"cpuset.cpus.effective": "0-1",
+			},
+			expectedID:    id,
+			expectedPodID: "1234-abcd",
+		},
+		{
+			// The IDs are not visible from a private cgroup namespace.
+			name:      "v2 private cgroup namespace",
+			unified:   true,
+			cgroup:    "0::/\n",
+			mountInfo: "30 22 0:26 / %[1]s ro,relatime - cgroup2 cgroup2 rw\n",
+			files: map[string]string{
+				"cpu.stat":              "usage_usec 100",
+				"cpuset.cpus.effective": "0-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writeProcFixture(t, tt.cgroup, tt.mountInfo, tt.files)
+
+			cg, err := newCGroupFrom(discardLogger, tt.unified, p)
+			require.NoError(t, err)
+
+			info, err := cgroupSource{cg: cg, log: discardLogger}.info()
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(p.mountPoint, tt.expectedPath), info.Path)
+			assert.Equal(t, tt.expectedID, info.ContainerID())
+			assert.Equal(t, tt.expectedPodID, info.PodUID())
+		})
+	}
+}
+
+func TestCGroupSourceOptionalStatistics(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"cpu.stat":       "usage_usec 100\nnr_periods 10\nnr_throttled 2\nthrottled_usec 5",
+		"memory.current": "invalid",
+		"pids.current":   "invalid",
+		"io.stat":        "8:0 rbytes=invalid",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r, err := cgroupSource{cg: &cgroupv2{dir: dir}, log: log}.read()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100*time.Microsecond), r.total)
+	assert.Equal(t, uint64(2), r.throttling.ThrottledPeriods)
+	assert.Equal(t, Memory{}, r.memory)
+	assert.Equal(t, PIDs{}, r.pids)
+	assert.Equal(t, IO{}, r.blockIO)
+	assert.Contains(t, buf.String(), `msg="cgroup: memory statistics unreadable"`)
+	assert.Contains(t, buf.String(), `msg="cgroup: pids statistics unreadable"`)
+	assert.Contains(t, buf.String(), `msg="cgroup: io statistics unreadable"`)
+
+	_, err = cgroupSource{cg: &cgroupv2{dir: filepath.Join(testDataCGroupsPath, "empty")}, log: log}.read()
+	assert.Error(t, err, "the CPU counters are required")
+}
+
+func TestCGroupV2CPUStat(t *testing.T) {
+	cg := &cgroupv2{dir: filepath.Join(testDataCGroupsPath, "v2")}
+
+	usage, err := cg.cpuUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20905476302*time.Microsecond), usage)
+
+	th, err := cg.throttling()
+	assert.NoError(t, err)
+	assert.Equal(t, Throttling{
+		Periods:          100,
+		ThrottledPeriods: 10,
+		ThrottledTime:    5 * time.Millisecond,
+		Bursts:           3,
+		BurstTime:        2 * time.Millisecond,
+	}, th)
+
+	counters, countersTh, err := cg.cpuCounters()
+	assert.NoError(t, err)
+	assert.Equal(t, usage, counters)
+	assert.Equal(t, th, countersTh)
+
+	_, _, err = (&cgroupv2{dir: filepath.Join(testDataCGroupsPath, "empty")}).cpuCounters()
+	assert.Error(t, err)
+
+	controllers, err := readControllers(cg.dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cpuset", "cpu", "io", "memory", "pids"}, controllers)
+}
+
+func TestCGroupV2Rootless(t *testing.T) {
+	dir := filepath.Join(testDataCGroupsPath, "rootless")
+	controllers, err := readControllers(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"memory", "pids"}, controllers)
+
+	_, err = readControllers(filepath.Join(testDataCGroupsPath, "empty"))
+	assert.Error(t, err)
+
+	// Each metric falls back on its own when its controller is not enabled.
+	cg := &cgroupv2{dir: dir, mount: dir, enabled: controllers}
+
+	usage, err := cg.cpuUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1500*time.Microsecond), usage)
+
+	th, err := cg.throttling()
+	assert.NoError(t, err)
+	assert.Equal(t, Throttling{}, th)
+
+	quota, _, err := cg.cpuQuota()
+	assert.NoError(t, err)
+	assert.Equal(t, -1.0, quota)
+
+	burst, err := cg.cpuBurst(dir)
+	assert.NoError(t, err)
+	assert.Zero(t, burst)
+
+	w, err := cg.cpuWeight()
+	assert.NoError(t, err)
+	assert.Equal(t, CPUWeight{}, w)
+
+	attrs, err := cg.schedAttrs()
+	assert.NoError(t, err)
+	assert.Nil(t, attrs)
+
+	online, err := readFirstLine(sysCPUOnlinePath)
+	if err != nil {
+		t.Skipf("%s: %v", sysCPUOnlinePath, err)
 	}
+	cpuset, err := cg.cpuset()
+	assert.NoError(t, err)
+	assert.Equal(t, online, cpuset)
 }
@@ -4,52 +4,142 @@
 package cgroups
 
 import (
+	"errors"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path"
+	"sort"
 	"strings"
+	"time"
 )
 
 type cgroupv1 struct {
+	// cgroups maps each controller to the directory of the cgroup.
 	cgroups map[string]string
+	// mounts maps each controller to its mount point.
+	mounts map[string]string
+	// cpuName is the path of the CPU controller cgroup in /proc/self/cgroup.
+	cpuName string
 }
 
-func newCGroupV1() (*cgroupv1, error) {
-	subsystems, err := parseCGroupSubsystems(procCGroupPath)
+func newCGroupV1(log *slog.Logger, p procPaths) (*cgroupv1, error) {
+	subsystems, err := parseCGroupSubsystems(p.cgroup)
 	if err != nil {
 		return nil, err
 	}
 
-	mountInfos, err := getMountInfos(procMountInfoPath, fsTypeFilter("cgroup"))
+	mountInfos, err := getMountInfos(p.mountInfo, FSTypeFilter("cgroup"))
 	if err != nil {
 		return nil, err
 	}
 
 	cgroups := make(map[string]string)
+	mounts := make(map[string]string)
+	resolved := make(map[string]bool)
 
 	for _, mountInfo := range mountInfos {
 		for _, opt := range mountInfo.SuperOptions {
-			_, exists := subsystems[opt]
-			if !exists {
+			subsys, exists := subsystems[opt]
+			if !exists || resolved[opt] {
 				continue
 			}
 
-			cgroups[opt] = path.Join(cgroupMountPoint, opt)
+			// A hierarchy may be mounted more than once, prefer the mount
+			// the cgroup is visible in.
+			dir, ok := resolveCGroupDir(subsys.Name, mountInfo)
+			if !ok {
+				log.Debug("cgroup: v1 cgroup outside of mount root, using mount point",
+					"controller", opt, "cgroup", subsys.Name, "root", mountInfo.Root, "mount", mountInfo.MountPoint)
+			}
+
+			cgroups[opt] = dir
+			mounts[opt] = mountInfo.MountPoint
+			resolved[opt] = ok
+			log.Debug("cgroup: v1 controller resolved", "controller", opt,
+				"dir", cgroups[opt], "mount", mounts[opt])
+		}
+	}
+
+	for _, controller := range []string{"cpu", "cpuacct", "cpuset"} {
+		if _, exists := cgroups[controller]; !exists {
+			log.Debug("cgroup: v1 controller not mounted", "controller", controller)
 		}
 	}
 
-	return &cgroupv1{
+	cg := &cgroupv1{
 		cgroups: cgroups,
-	}, nil
+		mounts:  mounts,
+	}
+	if subsys, exists := subsystems["cpu"]; exists {
+		cg.cpuName = subsys.Name
+	}
+
+	return cg, nil
 }
 
-// cpuQuota returns the CPU quota applied with the CPU cgroup controller.
-// It is a result of `cpu.cfs_quota_us / cpu.cfs_period_us`.
-func (cg *cgroupv1) cpuQuota() (float64, error) {
+func (cg *cgroupv1) version() int {
+	return 1
+}
+
+// path returns the directory of the CPU cgroup controller.
+func (cg *cgroupv1) path() string {
+	return cg.cgroups["cpu"]
+}
+
+// name returns the path of the CPU controller cgroup in /proc/self/cgroup.
+func (cg *cgroupv1) name() string {
+	return cg.cpuName
+}
+
+// controllers returns the mounted controllers the cgroup was resolved in.
+func (cg *cgroupv1) controllers() []string {
+	controllers := make([]string, 0, len(cg.cgroups))
+	for controller := range cg.cgroups {
+		controllers = append(controllers, controller)
+	}
+	sort.Strings(controllers)
+
+	return controllers
+}
+
+// cpuFiles returns the paths of the files the CPU configuration and usage
+// are read from, for the mounted controllers.
+func (cg *cgroupv1) cpuFiles() []string {
+	var files []string
+	for _, f := range []struct{ controller, file string }{
+		{"cpu", "cpu.cfs_quota_us"},
+		{"cpu", "cpu.cfs_period_us"},
+		{"cpu", "cpu.cfs_burst_us"},
+		{"cpu", "cpu.shares"},
+		{"cpu", "cpu.stat"},
+		{"cpuacct", "cpuacct.usage"},
+		{"cpuset", "cpuset.cpus"},
+	} {
+		if dir, exists := cg.cgroups[f.controller]; exists {
+			files = append(files, path.Join(dir, f.file))
+		}
+	}
+
+	return files
+}
+
+// cpuQuota returns the CPU quota applied with the CPU cgroup controller,
+// along with the path of the cgroup defining it. The quota of an ancestor
+// also applies to its descendants, so the smallest quota of the cgroup and
+// its ancestors up to the controller mount point is returned.
+func (cg *cgroupv1) cpuQuota() (float64, string, error) {
 	cpuCGroupPath, exists := cg.cgroups["cpu"]
 	if !exists {
-		return -1, nil
+		return -1, "", nil
 	}
 
+	return walkQuota(cpuCGroupPath, cg.mounts["cpu"], readCFSQuota)
+}
+
+// readCFSQuota returns the CPU quota of the CPU cgroup at dir.
+// It is a result of `cpu.cfs_quota_us / cpu.cfs_period_us`.
+func readCFSQuota(cpuCGroupPath string) (float64, error) {
 	cpuQuotaUs, err := readInt(path.Join(cpuCGroupPath, "cpu.cfs_quota_us"))
 	if defined := cpuQuotaUs > 0; err != nil || !defined {
 		return -1, err
@@ -63,6 +153,69 @@ func (cg *cgroupv1) cpuQuota() (float64, error) {
 	return float64(cpuQuotaUs) / float64(cpuPeriodUs), nil
 }
 
+// cpuBurst returns the CPU burst budget of the cgroup at dir in the CPU
+// cgroup controller, in cores, e.g. the cgroup the quota is read from. It
+// is a result of `cpu.cfs_burst_us / cpu.cfs_period_us`. cpu.cfs_burst_us
+// is available since Linux 5.14.
+func (cg *cgroupv1) cpuBurst(cpuCGroupPath string) (float64, error) {
+	if _, exists := cg.cgroups["cpu"]; !exists {
+		return 0, nil
+	}
+
+	cpuBurstUs, err := readInt(path.Join(cpuCGroupPath, "cpu.cfs_burst_us"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if cpuBurstUs <= 0 {
+		return 0, nil
+	}
+
+	cpuPeriodUs, err := readInt(path.Join(cpuCGroupPath, "cpu.cfs_period_us"))
+	if defined := cpuPeriodUs > 0; err != nil || !defined {
+		return 0, err
+	}
+
+	return float64(cpuBurstUs) / float64(cpuPeriodUs), nil
+}
+
+// cpuWeight returns the cpu.shares of the CPU cgroup controller, along with
+// the total shares of its siblings.
+// https://www.kernel.org/doc/Documentation/scheduler/sched-design-CFS.txt
+func (cg *cgroupv1) cpuWeight() (CPUWeight, error) {
+	cpuCGroupPath, exists := cg.cgroups["cpu"]
+	if !exists {
+		return CPUWeight{}, nil
+	}
+
+	text, err := readFirstLine(path.Join(cpuCGroupPath, "cpu.shares"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return CPUWeight{}, nil
+		}
+		return CPUWeight{}, err
+	}
+
+	var w CPUWeight
+	if w.Shares, err = parseUint(text); err != nil {
+		return CPUWeight{}, err
+	}
+
+	if w.SiblingsTotal, err = siblingWeights(cpuCGroupPath, "cpu.shares"); err != nil {
+		return CPUWeight{}, err
+	}
+
+	return w, nil
+}
+
+// schedAttrs returns nil, utilization clamping and cpu.idle are only
+// available on cgroup v2.
+func (cg *cgroupv1) schedAttrs() (*SchedAttrs, error) {
+	return nil, nil
+}
+
 // cpuUsage returns the CPU usage applied with the CPU cgroup controller.
 // cpuacct.usage gives the CPU time (in nanoseconds) obtained by this group
 // which is essentially the CPU time obtained by all the tasks
@@ -83,18 +236,23 @@ func (cg *cgroupv1) cpuUsage() (uint64, error) {
 	return parseUint(strings.TrimSpace(string(data)))
 }
 
-// effectiveCPUs returns the CPU effective for cgroup controller.
+// cpuset returns the CPU list of the cpuset cgroup controller.
 // cpuset.cpus is a list of the physical numbers of the CPUs on which
 // processes in that cpuset are allowed to execute.
 // https://man7.org/linux/man-pages/man7/cpuset.7.html
 // https://www.kernel.org/doc/Documentation/admin-guide/cgroup-v1/cpusets.rst
-func (cg *cgroupv1) effectiveCPUs() (int, error) {
+func (cg *cgroupv1) cpuset() (string, error) {
 	cpuCGroupPath, exists := cg.cgroups["cpuset"]
 	if !exists {
-		return 0, nil
+		return "", nil
 	}
 
-	data, err := readFirstLine(path.Join(cpuCGroupPath, "cpuset.cpus"))
+	return readFirstLine(path.Join(cpuCGroupPath, "cpuset.cpus"))
+}
+
+// effectiveCPUs returns the CPU effective for cgroup controller.
+func (cg *cgroupv1) effectiveCPUs() (int, error) {
+	data, err := cg.cpuset()
 	if err != nil {
 		return 0, err
 	}
@@ -106,3 +264,131 @@ func (cg *cgroupv1) effectiveCPUs() (int, error) {
 
 	return len(cpus), nil
 }
+
+// throttling returns the CFS bandwidth statistics of the CPU cgroup controller.
+// cpu.stat reports nr_periods, nr_throttled and throttled_time (in nanoseconds),
+// and nr_bursts and burst_time since Linux 5.14.
+// https://www.kernel.org/doc/Documentation/scheduler/sched-bwc.txt
+func (cg *cgroupv1) throttling() (Throttling, error) {
+	cpuCGroupPath, exists := cg.cgroups["cpu"]
+	if !exists {
+		return Throttling{}, nil
+	}
+
+	stats := make(map[string]string)
+	if err := readKVStatsFile(cpuCGroupPath, "cpu.stat", stats); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Throttling{}, nil
+		}
+		return Throttling{}, err
+	}
+
+	return parseThrottling(stats, "_time", time.Nanosecond)
+}
+
+// cpuCounters returns the CPU usage and the throttling statistics, read from
+// cpuacct.usage and the cpu.stat of the CPU cgroup controller.
+func (cg *cgroupv1) cpuCounters() (uint64, Throttling, error) {
+	usage, err := cg.cpuUsage()
+	if err != nil {
+		return 0, Throttling{}, err
+	}
+
+	throttling, err := cg.throttling()
+	if err != nil {
+		return 0, Throttling{}, err
+	}
+
+	return usage, throttling, nil
+}
+
+// memory returns the statistics of the memory cgroup controller, read from
+// memory.usage_in_bytes, memory.limit_in_bytes, memory.stat and the oom_kill
+// field of memory.oom_control.
+// https://www.kernel.org/doc/Documentation/cgroup-v1/memory.txt
+func (cg *cgroupv1) memory() (Memory, error) {
+	memCGroupPath, exists := cg.cgroups["memory"]
+	if !exists {
+		return Memory{}, nil
+	}
+
+	usage, err := readFirstLine(path.Join(memCGroupPath, "memory.usage_in_bytes"))
+	if err != nil {
+		return Memory{}, err
+	}
+
+	var m Memory
+	if m.Usage, err = parseUint(usage); err != nil {
+		return Memory{}, err
+	}
+
+	limit, err := readFirstLine(path.Join(memCGroupPath, "memory.limit_in_bytes"))
+	if err != nil {
+		return Memory{}, err
+	}
+	if m.Limit, err = parseUint(limit); err != nil {
+		return Memory{}, err
+	}
+	if m.Limit >= memoryUnlimited {
+		m.Limit = 0
+	}
+
+	if m.Stat, err = readKVUintFile(memCGroupPath, "memory.stat"); err != nil {
+		return Memory{}, err
+	}
+
+	// oom_kill was added to memory.oom_control in Linux 4.13.
+	oomControl, err := readKVUintFile(memCGroupPath, "memory.oom_control")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Memory{}, err
+	}
+	m.OOMKill = oomControl["oom_kill"]
+
+	return m, nil
+}
+
+// blockIO returns the statistics of the blkio cgroup controller, read from
+// blkio.throttle.io_service_bytes and blkio.throttle.io_serviced, which are
+// updated regardless of the IO scheduler in use.
+// https://www.kernel.org/doc/Documentation/cgroup-v1/blkio-controller.txt
+func (cg *cgroupv1) blockIO() (IO, error) {
+	blkioCGroupPath, exists := cg.cgroups["blkio"]
+	if !exists {
+		return IO{}, nil
+	}
+
+	devices := make(map[string]*IODevice)
+	for file, bytes := range map[string]bool{
+		"blkio.throttle.io_service_bytes": true,
+		"blkio.throttle.io_serviced":      false,
+	} {
+		data, err := os.ReadFile(path.Join(blkioCGroupPath, file))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return IO{}, err
+		}
+
+		if err := parseBlkioStat(string(data), devices, bytes); err != nil {
+			return IO{}, err
+		}
+	}
+
+	if len(devices) == 0 {
+		return IO{}, nil
+	}
+
+	return IO{Devices: sortedDevices(devices)}, nil
+}
+
+// pids returns the statistics of the pids cgroup controller.
+// https://www.kernel.org/doc/Documentation/cgroup-v1/pids.txt
+func (cg *cgroupv1) pids() (PIDs, error) {
+	pidsCGroupPath, exists := cg.cgroups["pids"]
+	if !exists {
+		return PIDs{}, nil
+	}
+
+	return readPIDs(pidsCGroupPath)
+}
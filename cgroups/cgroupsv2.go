@@ -8,47 +8,142 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const sysCPUOnlinePath = "/sys/devices/system/cpu/online"
+
 type cgroupv2 struct {
-	path    string
-	cgroups map[string]string
+	dir   string
+	mount string
+	// cgroupName is the path of the cgroup in /proc/self/cgroup.
+	cgroupName string
+	// enabled lists the controllers available in the cgroup, read from
+	// cgroup.controllers. It is nil if unknown.
+	enabled []string
+	// log receives the failures of the optional files, nil discards them.
+	log *slog.Logger
 }
 
-func newCGroupV2() (*cgroupv2, error) {
-	subsystems, err := parseCGroupSubsystems(procCGroupPath)
+func newCGroupV2(log *slog.Logger, p procPaths) (*cgroupv2, error) {
+	subsystems, err := readCGroups(p.cgroup)
 	if err != nil {
 		return nil, err
 	}
 
 	// For the cgroups version 2 hierarchy, this field contains the value 0
-	var v2subsys *Subsystem
-	for _, subsys := range subsystems {
-		if subsys.ID == 0 {
-			v2subsys = subsys
-			break
+	v2subsys := subsystems.Unified()
+	if v2subsys == nil {
+		return nil, errors.New("cgroupv2 subsystem is nil")
+	}
+	mount, path := p.mountPoint, filepath.Join(p.mountPoint, v2subsys.Name)
+	mountInfos, err := getMountInfos(p.mountInfo, FSTypeFilter("cgroup2"), MountPointFilter(p.mountPoint))
+	switch {
+	case err != nil:
+		log.Debug("cgroup: mountinfo unreadable, assuming the host cgroup namespace", "error", err)
+	case len(mountInfos) == 0:
+		log.Debug("cgroup: cgroup2 mount not found, assuming the host cgroup namespace", "mount", p.mountPoint)
+	default:
+		var ok bool
+		mount = mountInfos[0].MountPoint
+		if path, ok = resolveCGroupDir(v2subsys.Name, mountInfos[0]); !ok {
+			log.Debug("cgroup: v2 cgroup outside of mount root, using mount point",
+				"cgroup", v2subsys.Name, "root", mountInfos[0].Root, "mount", mount)
 		}
 	}
 
-	if v2subsys == nil {
-		return nil, errors.New("cgroupv2 subsystem is nil")
+	log.Debug("cgroup: v2 path resolved", "cgroup", v2subsys.Name, "dir", path)
+
+	// cpu.stat exists in every cgroup regardless of the controllers
+	// enabled, it is unreadable only if the path is wrong.
+	cg := &cgroupv2{dir: path, mount: mount, cgroupName: v2subsys.Name, log: log}
+	if _, err := cg.cpuStat(); err != nil {
+		log.Debug("cgroup: cpu.stat unreadable", "dir", path, "error", err)
+		return nil, err
 	}
-	cgroups := make(map[string]string)
-	path := filepath.Join(cgroupMountPoint, v2subsys.Name)
-	if err := readKVStatsFile(path, "cpu.stat", cgroups); err != nil {
+
+	// Under rootless containers and systemd user sessions, only the
+	// controllers delegated to the user are enabled. Each metric falls back
+	// on its own when the files of its controller are missing.
+	cg.enabled, err = readControllers(path)
+	if err != nil {
+		log.Debug("cgroup: cgroup.controllers unreadable", "dir", path, "error", err)
+	} else {
+		log.Debug("cgroup: v2 controllers available", "dir", path, "controllers", cg.enabled)
+		if !slices.Contains(cg.enabled, "cpu") {
+			log.Debug("cgroup: v2 cpu controller not enabled, weight and throttling unavailable")
+		}
+		if !slices.Contains(cg.enabled, "cpuset") {
+			log.Debug("cgroup: v2 cpuset controller not enabled, falling back to the online cpus",
+				"path", sysCPUOnlinePath)
+		}
+	}
+
+	return cg, nil
+}
+
+// readControllers returns the space separated controllers listed in the
+// cgroup.controllers file at dir, i.e. the controllers enabled in the
+// cgroup.subtree_control of its parent.
+func readControllers(dir string) ([]string, error) {
+	data, err := os.ReadFile(path.Join(dir, "cgroup.controllers"))
+	if err != nil {
 		return nil, err
 	}
 
-	return &cgroupv2{
-		path:    path,
-		cgroups: cgroups,
-	}, nil
+	// An empty file means no controller is enabled, not an unknown set.
+	return append([]string{}, strings.Fields(string(data))...), nil
+}
+
+func (cg *cgroupv2) version() int {
+	return 2
+}
+
+// path returns the directory of the unified cgroup.
+func (cg *cgroupv2) path() string {
+	return cg.dir
+}
+
+// name returns the path of the cgroup in /proc/self/cgroup.
+func (cg *cgroupv2) name() string {
+	return cg.cgroupName
+}
+
+// controllers returns the controllers available in the cgroup, nil if
+// unknown.
+func (cg *cgroupv2) controllers() []string {
+	return cg.enabled
+}
+
+// cpuFiles returns the paths of the files the CPU configuration and usage
+// are read from, along with the controllers enabled in the cgroup and for
+// its children.
+func (cg *cgroupv2) cpuFiles() []string {
+	files := []string{
+		"cgroup.controllers",
+		"cgroup.subtree_control",
+		"cpu.max",
+		"cpu.max.burst",
+		"cpu.weight",
+		"cpu.weight.nice",
+		"cpu.stat",
+		"cpu.uclamp.min",
+		"cpu.uclamp.max",
+		"cpu.idle",
+		"cpuset.cpus.effective",
+	}
+	for i, file := range files {
+		files[i] = path.Join(cg.dir, file)
+	}
+
+	return files
 }
 
 func readKVStatsFile(path string, file string, out map[string]string) error {
@@ -70,11 +165,38 @@ func readKVStatsFile(path string, file string, out map[string]string) error {
 	return s.Err()
 }
 
-// cpuQuota returns the CPU quota applied with the CPU cgroup2 controller.
+// readKVUintFile reads a flat-keyed cgroup file with unsigned values.
+func readKVUintFile(path string, file string) (map[string]uint64, error) {
+	stats := make(map[string]string)
+	if err := readKVStatsFile(path, file, stats); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]uint64, len(stats))
+	for key, value := range stats {
+		v, err := parseUint(value)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+
+	return out, nil
+}
+
+// cpuQuota returns the CPU quota applied with the CPU cgroup2 controller,
+// along with the path of the cgroup defining it. The quota of an ancestor
+// also applies to its descendants, so the smallest quota of the cgroup and
+// its ancestors up to the mount point is returned.
+func (cg *cgroupv2) cpuQuota() (float64, string, error) {
+	return walkQuota(cg.dir, cg.mount, readCPUMax)
+}
+
+// readCPUMax returns the CPU quota of the cgroup2 at dir.
 // It is a result of reading cpu quota and period from cpu.max file.
 // It will return `cpu.max / cpu.period`.
-func (cg *cgroupv2) cpuQuota() (float64, error) {
-	cpuMaxFile, err := os.Open(path.Join(cg.path, "cpu.max"))
+func readCPUMax(dir string) (float64, error) {
+	cpuMaxFile, err := os.Open(path.Join(dir, "cpu.max"))
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			return -1, nil
@@ -119,16 +241,142 @@ func (cg *cgroupv2) cpuQuota() (float64, error) {
 	return 0, errors.New("fail to parse cpu quota cgroupV2")
 }
 
+// cpuBurst returns the CPU burst budget of the cgroup2 at dir, in cores,
+// e.g. the cgroup the quota is read from. It is a result of
+// `cpu.max.burst / $PERIOD` where the period is read from the cpu.max of the
+// same cgroup. cpu.max.burst is available since Linux 5.14.
+func (cg *cgroupv2) cpuBurst(dir string) (float64, error) {
+	burst, err := readFirstLine(path.Join(dir, "cpu.max.burst"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	burstUs, err := parseUint(burst)
+	if err != nil || burstUs == 0 {
+		return 0, err
+	}
+
+	cpuMax, err := readFirstLine(path.Join(dir, "cpu.max"))
+	if err != nil {
+		return 0, err
+	}
+
+	// cpuMax has format: $MAX $PERIOD
+	fields := strings.Fields(cpuMax)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid cgroupV2 with line: %s", cpuMax)
+	}
+
+	period, err := parseUint(fields[1])
+	if err != nil {
+		return 0, err
+	}
+	if period == 0 {
+		return 0, errors.New("zero value for period is not allowed")
+	}
+
+	return float64(burstUs) / float64(period), nil
+}
+
+// cpuWeight returns the cpu.weight and cpu.weight.nice of the cgroup2 cpu
+// controller, along with the total weight of its siblings. The files do not
+// exist in the root cgroup.
+func (cg *cgroupv2) cpuWeight() (CPUWeight, error) {
+	text, err := readFirstLine(path.Join(cg.dir, "cpu.weight"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return CPUWeight{}, nil
+		}
+		return CPUWeight{}, err
+	}
+
+	var w CPUWeight
+	if w.Weight, err = parseUint(text); err != nil {
+		return CPUWeight{}, err
+	}
+
+	nice, err := readInt(path.Join(cg.dir, "cpu.weight.nice"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return CPUWeight{}, err
+	}
+	w.Nice = nice
+
+	if w.SiblingsTotal, err = siblingWeights(cg.dir, "cpu.weight"); err != nil {
+		return CPUWeight{}, err
+	}
+
+	return w, nil
+}
+
+// schedAttrs returns the utilization clamps from cpu.uclamp.min and
+// cpu.uclamp.max, which require CONFIG_UCLAMP_TASK_GROUP, and cpu.idle,
+// available since Linux 5.15. It returns nil when none of them exist.
+// https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#cpu-interface-files
+func (cg *cgroupv2) schedAttrs() (*SchedAttrs, error) {
+	attrs := &SchedAttrs{UclampMax: 100}
+	found := false
+
+	for file, dst := range map[string]*float64{
+		"cpu.uclamp.min": &attrs.UclampMin,
+		"cpu.uclamp.max": &attrs.UclampMax,
+	} {
+		text, err := readFirstLine(path.Join(cg.dir, file))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		found = true
+		if text == "max" {
+			*dst = 100
+			continue
+		}
+		if *dst, err = strconv.ParseFloat(text, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	idle, err := readInt(path.Join(cg.dir, "cpu.idle"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		found = true
+		attrs.Idle = idle == 1
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return attrs, nil
+}
+
 // cpuUsage returns the CPU total usage for cgroup2 controller.
 // It is a result of reading cpu usage from cpu.stat file with field usage_usec.
 // https://www.kernel.org/doc/Documentation/cgroup-v2.txt
 func (cg *cgroupv2) cpuUsage() (uint64, error) {
+	stats, err := cg.cpuStat()
+	if err != nil {
+		return 0, err
+	}
+
+	return parseCPUStatUsage(stats)
+}
+
+// parseCPUStatUsage returns the usage_usec field of cpu.stat in nanoseconds.
+func parseCPUStatUsage(stats map[string]string) (uint64, error) {
 	// Example of cpu.stat format:
 	// usage_usec 20905476302
 	// user_usec 20039242823
 	// system_usec 866233479
 	// All time durations are in microseconds.
-	usec, err := parseUint(cg.cgroups["usage_usec"])
+	usec, err := parseUint(stats["usage_usec"])
 	if err != nil {
 		return 0, err
 	}
@@ -136,13 +384,24 @@ func (cg *cgroupv2) cpuUsage() (uint64, error) {
 	return usec * uint64(time.Microsecond), nil
 }
 
-// effectiveCPUs returns the CPU effective for cgroup2 controller in cpuset.
+// cpuset returns the CPU list effective for cgroup2 controller in cpuset.
 // cpuset.cpus is a list of the physical numbers of the CPUs on which
 // processes in that cpuset are allowed to execute.
 // https://man7.org/linux/man-pages/man7/cpuset.7.html
 // https://www.kernel.org/doc/Documentation/admin-guide/cgroup-v1/cpusets.rst
+// cpuset.cpus.effective only exists when the cpuset controller is enabled,
+// otherwise the CPUs online on the host are returned.
+func (cg *cgroupv2) cpuset() (string, error) {
+	cpus, err := readFirstLine(path.Join(cg.dir, "cpuset.cpus.effective"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return readFirstLine(sysCPUOnlinePath)
+	}
+	return cpus, err
+}
+
+// effectiveCPUs returns the CPU effective for cgroup2 controller in cpuset.
 func (cg *cgroupv2) effectiveCPUs() (int, error) {
-	data, err := readFirstLine(path.Join(cg.path, "cpuset.cpus.effective"))
+	data, err := cg.cpuset()
 	if err != nil {
 		return 0, err
 	}
@@ -154,3 +413,160 @@ func (cg *cgroupv2) effectiveCPUs() (int, error) {
 
 	return len(cpus), nil
 }
+
+// throttling returns the CFS bandwidth statistics for cgroup2 controller.
+// The nr_periods, nr_throttled and throttled_usec fields of cpu.stat are
+// only present when the cpu controller is enabled, and nr_bursts and
+// burst_usec since Linux 5.14.
+func (cg *cgroupv2) throttling() (Throttling, error) {
+	stats, err := cg.cpuStat()
+	if err != nil {
+		return Throttling{}, err
+	}
+
+	return parseThrottling(stats, "_usec", time.Microsecond)
+}
+
+// cpuCounters returns the CPU usage and the throttling statistics from a
+// single read of cpu.stat, so both describe the same instant.
+func (cg *cgroupv2) cpuCounters() (uint64, Throttling, error) {
+	stats, err := cg.cpuStat()
+	if err != nil {
+		return 0, Throttling{}, err
+	}
+
+	usage, err := parseCPUStatUsage(stats)
+	if err != nil {
+		return 0, Throttling{}, err
+	}
+
+	throttling, err := parseThrottling(stats, "_usec", time.Microsecond)
+	if err != nil {
+		return 0, Throttling{}, err
+	}
+
+	return usage, throttling, nil
+}
+
+// cpuStat reads cpu.stat, which exists in every cgroup regardless of the
+// controllers enabled.
+func (cg *cgroupv2) cpuStat() (map[string]string, error) {
+	stats := make(map[string]string)
+	if err := readKVStatsFile(cg.dir, "cpu.stat", stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// parseThrottling builds Throttling from the key-value pairs of cpu.stat,
+// where the throttled and burst time fields are suffixed with timeSuffix and
+// expressed in unit.
+func parseThrottling(stats map[string]string, timeSuffix string, unit time.Duration) (Throttling, error) {
+	var (
+		t         Throttling
+		throttled uint64
+		burst     uint64
+		err       error
+	)
+
+	for key, dst := range map[string]*uint64{
+		"nr_periods":             &t.Periods,
+		"nr_throttled":           &t.ThrottledPeriods,
+		"nr_bursts":              &t.Bursts,
+		"throttled" + timeSuffix: &throttled,
+		"burst" + timeSuffix:     &burst,
+	} {
+		v, exists := stats[key]
+		if !exists {
+			continue
+		}
+		if *dst, err = parseUint(v); err != nil {
+			return Throttling{}, err
+		}
+	}
+
+	t.ThrottledTime = time.Duration(throttled) * unit
+	t.BurstTime = time.Duration(burst) * unit
+
+	return t, nil
+}
+
+// memory returns the statistics of the cgroup2 memory controller, read from
+// memory.current, memory.max, memory.stat and memory.events. The files are
+// only present when the memory controller is enabled.
+// https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
+func (cg *cgroupv2) memory() (Memory, error) {
+	current, err := readFirstLine(path.Join(cg.dir, "memory.current"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Memory{}, nil
+		}
+		return Memory{}, err
+	}
+
+	var m Memory
+	if m.Usage, err = parseUint(current); err != nil {
+		return Memory{}, err
+	}
+
+	limit, err := readFirstLine(path.Join(cg.dir, "memory.max"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Memory{}, err
+	}
+	if err == nil && limit != "max" {
+		if m.Limit, err = parseUint(limit); err != nil {
+			return Memory{}, err
+		}
+	}
+
+	if m.Stat, err = readKVUintFile(cg.dir, "memory.stat"); err != nil {
+		return Memory{}, err
+	}
+
+	events, err := readKVUintFile(cg.dir, "memory.events")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Memory{}, err
+	}
+	m.OOM = events["oom"]
+	m.OOMKill = events["oom_kill"]
+
+	return m, nil
+}
+
+// blockIO returns the statistics of the cgroup2 io controller, read from
+// io.stat, along with the IO pressure from io.pressure.
+// https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#io-interface-files
+func (cg *cgroupv2) blockIO() (IO, error) {
+	var blkio IO
+
+	data, err := os.ReadFile(path.Join(cg.dir, "io.stat"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return IO{}, err
+	}
+	if err == nil {
+		if blkio.Devices, err = parseIOStat(string(data)); err != nil {
+			return IO{}, err
+		}
+	}
+
+	// io.pressure requires CONFIG_PSI. Before Linux 5.17, it exists but
+	// cannot be read when PSI is disabled at boot with psi=0.
+	data, err = os.ReadFile(path.Join(cg.dir, "io.pressure"))
+	if err == nil {
+		var pressure Pressure
+		if pressure, err = parsePressure(string(data)); err == nil {
+			blkio.Pressure = &pressure
+		}
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		loggerOrDiscard(cg.log).Debug("cgroup: io.pressure unavailable", "dir", cg.dir, "error", err)
+	}
+
+	return blkio, nil
+}
+
+// pids returns the statistics of the cgroup2 pids controller.
+// https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#pid
+func (cg *cgroupv2) pids() (PIDs, error) {
+	return readPIDs(cg.dir)
+}
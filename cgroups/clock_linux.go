@@ -0,0 +1,75 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	procAuxvPath = "/proc/self/auxv"
+
+	// defaultClockTicks is the USER_HZ value of most kernels, used when
+	// the auxiliary vector cannot be read.
+	defaultClockTicks = 100
+
+	// atClkTck is the AT_CLKTCK auxiliary vector entry from <elf.h>.
+	atClkTck = 17
+)
+
+var (
+	clockTicksOnce      sync.Once
+	clockTicksPerSecond uint64
+)
+
+// clockTicks returns the number of clock ticks per second (USER_HZ) used by
+// the kernel for tick-based counters such as /proc/stat and /proc/<pid>/stat.
+// It is read once from AT_CLKTCK, falling back to defaultClockTicks.
+func clockTicks() uint64 {
+	clockTicksOnce.Do(func() {
+		clockTicksPerSecond = defaultClockTicks
+
+		data, err := os.ReadFile(procAuxvPath)
+		if err != nil {
+			return
+		}
+
+		hz, err := parseAuxv(data, atClkTck)
+		if err == nil && hz > 0 {
+			clockTicksPerSecond = hz
+		}
+	})
+
+	return clockTicksPerSecond
+}
+
+// parseAuxv returns the value of key from the auxiliary vector in data, a
+// sequence of native-endian word-sized (key, value) pairs terminated by
+// AT_NULL.
+// https://man7.org/linux/man-pages/man3/getauxval.3.html
+func parseAuxv(data []byte, key uint64) (uint64, error) {
+	wordSize := strconv.IntSize / 8
+	word := func(b []byte) uint64 {
+		if wordSize == 4 {
+			return uint64(binary.NativeEndian.Uint32(b))
+		}
+		return binary.NativeEndian.Uint64(b)
+	}
+
+	for i := 0; i+2*wordSize <= len(data); i += 2 * wordSize {
+		k := word(data[i:])
+		if k == 0 {
+			break
+		}
+		if k == key {
+			return word(data[i+wordSize:]), nil
+		}
+	}
+
+	return 0, errors.New("auxv: key not found")
+}
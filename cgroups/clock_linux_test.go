@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeAuxv(pairs ...uint64) []byte {
+	var data []byte
+	for _, v := range pairs {
+		if strconv.IntSize == 32 {
+			data = binary.NativeEndian.AppendUint32(data, uint32(v))
+		} else {
+			data = binary.NativeEndian.AppendUint64(data, v)
+		}
+	}
+	return data
+}
+
+func TestParseAuxv(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		expectedValue uint64
+		hasErr        bool
+	}{
+		{
+			name:          "found",
+			data:          encodeAuxv(6, 4096, atClkTck, 250, 0, 0),
+			expectedValue: 250,
+		},
+		{
+			name:   "after-null",
+			data:   encodeAuxv(6, 4096, 0, 0, atClkTck, 250),
+			hasErr: true,
+		},
+		{
+			name:   "truncated",
+			data:   encodeAuxv(6, 4096, atClkTck)[:5],
+			hasErr: true,
+		},
+		{
+			name:   "empty",
+			hasErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		value, err := parseAuxv(tt.data, atClkTck)
+		assert.Equal(t, tt.expectedValue, value, tt.name)
+
+		if tt.hasErr {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+	}
+}
+
+func TestClockTicks(t *testing.T) {
+	assert.Positive(t, clockTicks())
+}
@@ -0,0 +1,312 @@
+package cgroups
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const defaultHistorySize = 60
+
+// reading is a raw snapshot of the cgroup and host CPU counters.
+type reading struct {
+	total   uint64
+	system  uint64
+	process uint64
+	// waits is the run queue wait of each thread of the process, by TID.
+	waits      map[int]time.Duration
+	onlineCPUs uint64
+	throttling Throttling
+	memory     Memory
+	blockIO    IO
+	pids       PIDs
+	runtime    RuntimeCPU
+	// noSystem and noProcess report that the host and process counters
+	// could not be read, in which case the usages depending on them are
+	// not computed.
+	noSystem  bool
+	noProcess bool
+}
+
+// source provides the readings a Collector computes samples from.
+type source interface {
+	info() (Info, error)
+	read() (reading, error)
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithHistorySize sets the number of samples kept by the Collector.
+func WithHistorySize(n int) Option {
+	return func(c *Collector) {
+		if n > 0 {
+			c.historySize = n
+		}
+	}
+}
+
+// WithBurstLimit computes the percent of limit against the CPU quota plus
+// the burst budget, rather than the quota alone.
+func WithBurstLimit() Option {
+	return func(c *Collector) {
+		c.burstLimit = true
+	}
+}
+
+// WithLogger logs the cgroup detection decisions at debug level and the
+// failed collections of Run at warn level to l.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Collector) {
+		c.log = loggerOrDiscard(l)
+	}
+}
+
+// A Collector samples the CPU usage of the container and keeps a bounded
+// history of the samples. It is safe for concurrent use.
+type Collector struct {
+	src         source
+	historySize int
+	burstLimit  bool
+	log         *slog.Logger
+
+	// collectMu serializes Collect, so that readings are applied in the
+	// order they are taken. mu guards the state below.
+	collectMu sync.Mutex
+	mu        sync.Mutex
+	info      Info
+	prev      reading
+	history   []Sample
+	next      int
+	observers []func(Sample)
+
+	// runtimeWindow and runtime hold the Go runtime breakdown, which is
+	// only refreshed at garbage collections.
+	runtimeWindow runtimeWindow
+	runtime       RuntimeUsage
+}
+
+// NewCollector returns a Collector for the cgroup of the current process.
+func NewCollector(opts ...Option) (*Collector, error) {
+	c := newCollectorWithOptions(opts)
+
+	src, err := newSource(c.log)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.init(src)
+}
+
+func newCollector(src source, opts ...Option) (*Collector, error) {
+	return newCollectorWithOptions(opts).init(src)
+}
+
+func newCollectorWithOptions(opts []Option) *Collector {
+	c := &Collector{
+		historySize: defaultHistorySize,
+		log:         defaultLogger(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// init reads the cgroup information and the initial counters from src.
+func (c *Collector) init(src source) (*Collector, error) {
+	c.src = src
+
+	info, err := src.info()
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := src.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.burstLimit && info.Quota > 0 && info.Burst > 0 {
+		info.Limit = info.Quota + info.Burst
+		if info.EffectiveCPUs > 0 {
+			info.Limit = min(info.Limit, float64(info.EffectiveCPUs))
+		}
+	}
+
+	c.info = info
+	c.prev = prev
+	c.runtimeWindow = runtimeWindow{runtime: prev.runtime, total: prev.total}
+	c.history = make([]Sample, 0, c.historySize)
+
+	return c, nil
+}
+
+// Info returns the cgroup information detected when the Collector was created.
+func (c *Collector) Info() Info {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.info
+}
+
+// Collect reads the CPU counters and returns the usage since the previous
+// call, or since the Collector was created.
+func (c *Collector) Collect() (Sample, error) {
+	c.collectMu.Lock()
+	defer c.collectMu.Unlock()
+
+	r, err := c.src.read()
+	if err != nil {
+		return Sample{}, err
+	}
+
+	c.mu.Lock()
+
+	cpuCores := float64(r.onlineCPUs)
+	if cpuCores == 0.0 {
+		cpuCores = float64(c.info.EffectiveCPUs)
+	}
+
+	var (
+		cpuDelta     = float64(r.total) - float64(c.prev.total)
+		systemDelta  = float64(r.system) - float64(c.prev.system)
+		processDelta = float64(r.process) - float64(c.prev.process)
+		wait         = threadsWait(r.waits, c.prev.waits)
+	)
+	if r.noSystem || c.prev.noSystem {
+		systemDelta = 0
+	}
+	if r.noProcess || c.prev.noProcess {
+		processDelta, wait = 0, 0
+	}
+
+	usage, percent := computeCPUUsage(cpuDelta, systemDelta, cpuCores, float64(c.info.EffectiveCPUs), c.info.Limit)
+	processUsage, _ := computeCPUUsage(processDelta, systemDelta, cpuCores, 0, 0)
+
+	s := Sample{
+		Time:         time.Now(),
+		Usage:        usage,
+		Percent:      percent,
+		CPUTime:      time.Duration(r.total),
+		ProcessUsage: processUsage,
+		RunQueueWait: wait,
+		Throttled:    r.throttling.Sub(c.prev.throttling).ThrottledTime,
+		Throttling:   r.throttling,
+		Memory:       r.memory,
+		IO:           r.blockIO,
+		PIDs:         r.pids,
+	}
+	if cpuDelta > 0 && processDelta > 0 {
+		s.ProcessShare = min(processDelta/cpuDelta, 1)
+	}
+	if runtime, next, ok := c.runtimeWindow.advance(r); ok {
+		c.runtime, c.runtimeWindow = runtime, next
+	}
+	s.Runtime = c.runtime
+
+	c.prev = r
+	c.record(s)
+	observers := c.observers
+
+	c.mu.Unlock()
+
+	for _, fn := range observers {
+		fn(s)
+	}
+
+	return s, nil
+}
+
+// OnSample registers fn to be called with every collected sample.
+// fn is called synchronously from Collect and must not block.
+func (c *Collector) OnSample(fn func(Sample)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.observers = append(c.observers, fn)
+}
+
+// onSampleLatest registers fn like OnSample and returns the latest sample
+// under the same lock, so that every later sample is passed to fn.
+func (c *Collector) onSampleLatest(fn func(Sample)) (Sample, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.observers = append(c.observers, fn)
+	return c.latest()
+}
+
+// Latest returns the most recent sample, if any.
+func (c *Collector) Latest() (Sample, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.latest()
+}
+
+// latest returns the most recent sample. c.mu must be held.
+func (c *Collector) latest() (Sample, bool) {
+	if len(c.history) == 0 {
+		return Sample{}, false
+	}
+
+	return c.history[(c.next+len(c.history)-1)%len(c.history)], true
+}
+
+// History returns the retained samples, oldest first.
+func (c *Collector) History() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	history := make([]Sample, 0, len(c.history))
+	if len(c.history) < c.historySize {
+		return append(history, c.history...)
+	}
+
+	history = append(history, c.history[c.next:]...)
+	return append(history, c.history[:c.next]...)
+}
+
+// Run collects a sample every interval until ctx is done.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Collect(); err != nil {
+				c.log.Warn("cgroup: collect failed", "error", err)
+			}
+		}
+	}
+}
+
+// record appends s to the history ring, overwriting the oldest sample
+// once it is full. c.mu must be held.
+func (c *Collector) record(s Sample) {
+	if len(c.history) < c.historySize {
+		c.history = append(c.history, s)
+		c.next = len(c.history) % c.historySize
+		return
+	}
+
+	c.history[c.next] = s
+	c.next = (c.next + 1) % c.historySize
+}
+
+// threadsWait returns the run queue wait of the threads in waits since prev.
+// Threads that exited since prev are not subtracted, and those started since
+// are counted from 0.
+func threadsWait(waits, prev map[int]time.Duration) time.Duration {
+	var wait time.Duration
+	for tid, w := range waits {
+		wait += max(w-prev[tid], 0)
+	}
+	return wait
+}
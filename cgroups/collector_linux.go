@@ -0,0 +1,137 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import "log/slog"
+
+// cgroupSource reads the counters of the cgroup the current process
+// belongs to, along with the host counters from /proc/stat. The cgroup is
+// resolved once, when the source is created.
+type cgroupSource struct {
+	cg  cgroup
+	log *slog.Logger
+}
+
+func newSource(log *slog.Logger) (source, error) {
+	cg, err := newCGroup(log)
+	if err != nil {
+		log.Warn("cgroup: detection failed", "error", err)
+		return nil, err
+	}
+
+	return cgroupSource{cg: cg, log: log}, nil
+}
+
+func (s cgroupSource) info() (Info, error) {
+	cg := s.cg
+
+	cpuset, err := cg.cpuset()
+	if err != nil {
+		return Info{}, err
+	}
+
+	cpus, err := parseUints(cpuset)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info := Info{
+		Version:       cg.version(),
+		Path:          cg.path(),
+		CGroup:        cg.name(),
+		Controllers:   cg.controllers(),
+		Quota:         -1,
+		CPUSet:        cpuset,
+		EffectiveCPUs: len(cpus),
+		Limit:         float64(len(cpus)),
+	}
+
+	if info.Namespace, err = cgroupNamespace(); err != nil {
+		s.log.Debug("cgroup: namespace unreadable", "error", err)
+	}
+	info.PrivateNamespace = info.Namespace != 0 && info.Namespace != initCGroupNSInode
+
+	quota, quotaPath, err := cg.cpuQuota()
+	switch {
+	case err != nil:
+		s.log.Debug("cgroup: cpu quota unreadable, limiting to cpuset", "cpuset", cpuset, "error", err)
+	case quota <= 0:
+		s.log.Debug("cgroup: no cpu quota, limiting to cpuset", "cpuset", cpuset)
+	default:
+		info.Quota = quota
+		info.QuotaPath = quotaPath
+		if quota < info.Limit {
+			info.Limit = quota
+		}
+	}
+
+	// The burst budget only applies with the quota of the same cgroup.
+	if info.Quota > 0 {
+		if info.Burst, err = cg.cpuBurst(info.QuotaPath); err != nil {
+			s.log.Debug("cgroup: cpu burst unreadable", "path", info.QuotaPath, "error", err)
+		}
+	}
+
+	weight, err := cg.cpuWeight()
+	if err != nil {
+		s.log.Debug("cgroup: cpu weight unreadable", "error", err)
+	} else {
+		weight.GuaranteedCores = min(weight.guaranteedCores(float64(info.EffectiveCPUs)), info.Limit)
+		info.Weight = weight
+	}
+
+	if info.Sched, err = cg.schedAttrs(); err != nil {
+		return Info{}, err
+	}
+
+	s.log.Debug("cgroup: detected", "version", info.Version, "path", info.Path,
+		"namespace", info.Namespace, "private_namespace", info.PrivateNamespace,
+		"quota", info.Quota, "quota_path", info.QuotaPath, "cpuset", info.CPUSet, "limit", info.Limit)
+
+	return info, nil
+}
+
+// read returns the CPU counters of the cgroup, failing if they cannot be
+// read. The other statistics are optional: a failure is logged at debug
+// level and leaves them zero.
+func (s cgroupSource) read() (reading, error) {
+	cg := s.cg
+
+	total, throttling, err := cg.cpuCounters()
+	if err != nil {
+		return reading{}, err
+	}
+
+	r := reading{
+		total:      total,
+		throttling: throttling,
+		runtime:    ReadRuntimeCPU(),
+	}
+
+	if r.memory, err = cg.memory(); err != nil {
+		s.log.Debug("cgroup: memory statistics unreadable", "error", err)
+	}
+
+	if r.blockIO, err = cg.blockIO(); err != nil {
+		s.log.Debug("cgroup: io statistics unreadable", "error", err)
+	}
+
+	if r.pids, err = cg.pids(); err != nil {
+		s.log.Debug("cgroup: pids statistics unreadable", "error", err)
+	}
+
+	if r.system, r.onlineCPUs, err = systemCPUUsage(); err != nil {
+		s.log.Debug("cgroup: host cpu usage unreadable", "error", err)
+		r.noSystem = true
+	}
+
+	if process, waits, err := readProcessCounters(procSelfPath); err != nil {
+		s.log.Debug("cgroup: process cpu usage unreadable", "error", err)
+		r.noProcess = true
+	} else {
+		r.process, r.waits = uint64(process), waits
+	}
+
+	return r, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package cgroups
+
+import (
+	"errors"
+	"log/slog"
+)
+
+func newSource(*slog.Logger) (source, error) {
+	return nil, errors.ErrUnsupported
+}
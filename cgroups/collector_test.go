@@ -0,0 +1,282 @@
+package cgroups
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource replays a fixed sequence of readings.
+type fakeSource struct {
+	infoValue Info
+	readings  []reading
+}
+
+func (f *fakeSource) info() (Info, error) {
+	return f.infoValue, nil
+}
+
+func (f *fakeSource) read() (reading, error) {
+	r := f.readings[0]
+	if len(f.readings) > 1 {
+		f.readings = f.readings[1:]
+	}
+	return r, nil
+}
+
+func newFakeSource(totals ...uint64) *fakeSource {
+	src := &fakeSource{
+		infoValue: Info{
+			Version:       2,
+			Path:          "/sys/fs/cgroup",
+			Quota:         2,
+			CPUSet:        "0-3",
+			EffectiveCPUs: 4,
+			Limit:         2,
+		},
+	}
+	for i, total := range totals {
+		src.readings = append(src.readings, reading{
+			total:      total,
+			system:     uint64(i) * 4 * uint64(time.Second),
+			process:    total / 2,
+			waits:      map[int]time.Duration{1: time.Duration(i) * time.Millisecond},
+			onlineCPUs: 4,
+			throttling: Throttling{ThrottledTime: time.Duration(i) * 2 * time.Millisecond},
+			runtime:    RuntimeCPU{GC: time.Duration(total / 4), User: time.Duration(total / 4)},
+		})
+	}
+	return src
+}
+
+func TestCollectorCollect(t *testing.T) {
+	src := newFakeSource(0, uint64(time.Second), uint64(3*time.Second))
+
+	c, err := newCollector(src)
+	require.NoError(t, err)
+
+	_, ok := c.Latest()
+	assert.False(t, ok)
+
+	var observed []Sample
+	c.OnSample(func(s Sample) {
+		observed = append(observed, s)
+	})
+
+	s, err := c.Collect()
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, s.Usage, 1e-9)
+	assert.InDelta(t, 50.0, s.Percent, 1e-9)
+	assert.InDelta(t, 0.5, s.ProcessUsage, 1e-9)
+	assert.InDelta(t, 0.5, s.ProcessShare, 1e-9)
+	assert.Equal(t, time.Millisecond, s.RunQueueWait)
+	assert.Equal(t, 2*time.Millisecond, s.Throttled)
+	assert.InDelta(t, 25.0, s.Runtime.GC, 1e-9)
+	assert.InDelta(t, 25.0, s.Runtime.User, 1e-9)
+
+	s, err = c.Collect()
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, s.Usage, 1e-9)
+	assert.InDelta(t, 100.0, s.Percent, 1e-9)
+
+	latest, ok := c.Latest()
+	assert.True(t, ok)
+	assert.Equal(t, s, latest)
+	assert.Equal(t, src.infoValue, c.Info())
+	assert.Len(t, observed, 2)
+}
+
+func TestCollectorMissingHostCounters(t *testing.T) {
+	src := newFakeSource(0, uint64(time.Second), uint64(2*time.Second), uint64(3*time.Second))
+	src.readings[1].system, src.readings[1].noSystem = 0, true
+	src.readings[1].process, src.readings[1].waits, src.readings[1].noProcess = 0, nil, true
+
+	c, err := newCollector(src)
+	require.NoError(t, err)
+
+	// The CPU counters are still reported, without the usages depending on
+	// the missing counters.
+	s, err := c.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, s.CPUTime)
+	assert.Equal(t, 2*time.Millisecond, s.Throttled)
+	assert.Zero(t, s.Usage)
+	assert.Zero(t, s.ProcessUsage)
+	assert.Zero(t, s.RunQueueWait)
+
+	// The previous reading is missing them too.
+	s, err = c.Collect()
+	require.NoError(t, err)
+	assert.Zero(t, s.Usage)
+	assert.Zero(t, s.ProcessUsage)
+	assert.Zero(t, s.RunQueueWait)
+
+	s, err = c.Collect()
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, s.Usage, 1e-9)
+	assert.InDelta(t, 0.5, s.ProcessUsage, 1e-9)
+	assert.Equal(t, time.Millisecond, s.RunQueueWait)
+}
+
+func TestCollectorRunQueueWaitThreadExit(t *testing.T) {
+	src := newFakeSource(0, uint64(time.Second), uint64(2*time.Second))
+	src.readings[0].waits = map[int]time.Duration{1: 0, 2: 5 * time.Millisecond}
+	src.readings[1].waits = map[int]time.Duration{1: time.Millisecond, 2: 8 * time.Millisecond}
+	// Thread 2 exited and thread 3 started.
+	src.readings[2].waits = map[int]time.Duration{1: 2 * time.Millisecond, 3: 4 * time.Millisecond}
+
+	c, err := newCollector(src)
+	require.NoError(t, err)
+
+	s, err := c.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Millisecond, s.RunQueueWait)
+
+	s, err = c.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Millisecond, s.RunQueueWait)
+}
+
+// countingSource returns increasing counters, one second of CPU time per
+// reading over four seconds of host time.
+type countingSource struct {
+	n uint64
+}
+
+func (c *countingSource) info() (Info, error) {
+	return Info{EffectiveCPUs: 4, Limit: 4}, nil
+}
+
+func (c *countingSource) read() (reading, error) {
+	c.n++
+	return reading{
+		total:      c.n * uint64(time.Second),
+		system:     c.n * 4 * uint64(time.Second),
+		onlineCPUs: 4,
+	}, nil
+}
+
+func TestCollectorConcurrentCollect(t *testing.T) {
+	c, err := newCollector(&countingSource{}, WithHistorySize(100))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, _ = c.Collect()
+			}
+		}()
+	}
+	wg.Wait()
+
+	history := c.History()
+	require.Len(t, history, 80)
+	for _, s := range history {
+		assert.InDelta(t, 1.0, s.Usage, 1e-9)
+	}
+}
+
+func TestCollectorBurstLimit(t *testing.T) {
+	src := newFakeSource(0, uint64(time.Second))
+	src.infoValue.Burst = 1
+
+	c, err := newCollector(src, WithBurstLimit())
+	require.NoError(t, err)
+	assert.InDelta(t, 3.0, c.Info().Limit, 1e-9)
+
+	s, err := c.Collect()
+	require.NoError(t, err)
+	assert.InDelta(t, 100.0/3, s.Percent, 1e-9)
+
+	src.infoValue.Burst = 4
+	c, err = newCollector(src, WithBurstLimit())
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0, c.Info().Limit, 1e-9)
+}
+
+func TestCollectorHistory(t *testing.T) {
+	src := newFakeSource(0, 1, 2, 3, 4, 5)
+
+	c, err := newCollector(src, WithHistorySize(3))
+	require.NoError(t, err)
+	assert.Empty(t, c.History())
+
+	var collected []Sample
+	for i := 0; i < 5; i++ {
+		s, err := c.Collect()
+		require.NoError(t, err)
+		collected = append(collected, s)
+
+		latest, ok := c.Latest()
+		assert.True(t, ok)
+		assert.Equal(t, s, latest)
+	}
+
+	assert.Equal(t, collected[2:], c.History())
+}
+
+func TestThrottlingSub(t *testing.T) {
+	cur := Throttling{Periods: 30, ThrottledPeriods: 6, ThrottledTime: 3 * time.Second}
+	prev := Throttling{Periods: 10, ThrottledPeriods: 1, ThrottledTime: time.Second}
+
+	delta := cur.Sub(prev)
+	assert.Equal(t, Throttling{Periods: 20, ThrottledPeriods: 5, ThrottledTime: 2 * time.Second}, delta)
+	assert.InDelta(t, 0.25, delta.Ratio(), 1e-9)
+	assert.Equal(t, Throttling{}, prev.Sub(cur))
+	assert.Zero(t, Throttling{}.Ratio())
+}
+
+func TestRunQueueCorrelation(t *testing.T) {
+	samples := func(pairs ...time.Duration) []Sample {
+		var s []Sample
+		for i := 0; i < len(pairs); i += 2 {
+			s = append(s, Sample{RunQueueWait: pairs[i], Throttled: pairs[i+1]})
+		}
+		return s
+	}
+
+	assert.InDelta(t, 1.0, RunQueueCorrelation(samples(1, 10, 2, 20, 3, 30)), 1e-9)
+	assert.InDelta(t, -1.0, RunQueueCorrelation(samples(1, 30, 2, 20, 3, 10)), 1e-9)
+	assert.Zero(t, RunQueueCorrelation(samples(1, 0, 2, 0, 3, 0)))
+	assert.Zero(t, RunQueueCorrelation(samples(1, 0)))
+}
+
+func TestReadRuntimeCPU(t *testing.T) {
+	runtimeCPU := ReadRuntimeCPU()
+	assert.GreaterOrEqual(t, runtimeCPU.Total, runtimeCPU.GC+runtimeCPU.User)
+	assert.Equal(t, RuntimeUsage{}, runtimeUsage(runtimeCPU, RuntimeCPU{}, 0))
+}
+
+func TestCollectorRuntimeWindow(t *testing.T) {
+	// The runtime classes are refreshed at the first and fourth readings
+	// only: the GC used 1s of the 4s of container CPU in between.
+	src := newFakeSource(0, uint64(time.Second), uint64(2*time.Second), uint64(3*time.Second), uint64(4*time.Second))
+	for i := range src.readings {
+		src.readings[i].runtime = RuntimeCPU{Total: time.Second, GC: 0}
+	}
+	src.readings[4].runtime = RuntimeCPU{Total: 5 * time.Second, GC: time.Second}
+
+	c, err := newCollector(src)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		s, err := c.Collect()
+		require.NoError(t, err)
+		assert.Zero(t, s.Runtime.GC, "no refresh yet")
+	}
+
+	s, err := c.Collect()
+	require.NoError(t, err)
+	assert.InDelta(t, 25.0, s.Runtime.GC, 1e-9)
+
+	// The breakdown is carried over until the next refresh.
+	s, err = c.Collect()
+	require.NoError(t, err)
+	assert.InDelta(t, 25.0, s.Runtime.GC, 1e-9)
+}
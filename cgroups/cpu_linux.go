@@ -6,15 +6,11 @@ package cgroups
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
-)
-
-const (
-	clockTicksPerSecond = 100
 )
 
 var (
@@ -33,7 +29,7 @@ func CollectCPUUsage() (float64, float64) {
 		return 0, 0
 	}
 
-	total, err := cpuUsage()
+	total, err := cpuUsage(discardLogger)
 	if err != nil {
 		return 0, 0
 	}
@@ -53,8 +49,6 @@ func CollectCPUUsage() (float64, float64) {
 
 func calculateCPUUsage(total, system, onlineCPUs uint64) (float64, float64) {
 	var (
-		cpuUsage    = 0.0
-		cpuPercent  = 0.0
 		cpuDelta    = float64(total) - float64(preTotal)
 		systemDelta = float64(system) - float64(preSystem)
 		cpuCores    = float64(onlineCPUs)
@@ -64,43 +58,45 @@ func calculateCPUUsage(total, system, onlineCPUs uint64) (float64, float64) {
 		cpuCores = float64(cores)
 	}
 
-	if systemDelta > 0 && cpuDelta > 0 {
-		cpuUsage = (cpuDelta / systemDelta) * cpuCores
-		if limit > 0 {
-			cpuPercent = cpuDelta * float64(cores) * 100 / (systemDelta * limit)
-		}
-	}
-
-	return cpuUsage, cpuPercent
+	return computeCPUUsage(cpuDelta, systemDelta, cpuCores, float64(cores), limit)
 }
 
 func initializeOnce() {
 	initOnce.Do(func() {
+		log := defaultLogger()
 		defer func() {
 			if p := recover(); p != nil {
+				log.Warn("cgroup: initialization panicked, CPU usage disabled", "panic", p)
 				noCgroup = true
 			}
 		}()
 
-		if err := initialize(); err != nil {
+		if err := initialize(log); err != nil {
+			log.Warn("cgroup: initialization failed, CPU usage disabled", "error", err)
 			noCgroup = true
+			return
 		}
+
+		log.Debug("cgroup: initialized", "cores", cores, "limit", limit)
 	})
 }
 
-func initialize() error {
-	cpus, err := effectiveCpus()
+func initialize(log *slog.Logger) error {
+	cpus, err := effectiveCpus(log)
 	if err != nil {
 		return err
 	}
 
 	cores = uint64(cpus)
 	limit = float64(cpus)
-	quota, err := cpuQuota()
-	if err == nil && quota > 0 {
-		if quota < limit {
-			limit = quota
-		}
+	quota, err := cpuQuota(log)
+	switch {
+	case err != nil:
+		log.Debug("cgroup: cpu quota unreadable, limiting to effective cpus", "cpus", cpus, "error", err)
+	case quota <= 0:
+		log.Debug("cgroup: no cpu quota, limiting to effective cpus", "cpus", cpus)
+	case quota < limit:
+		limit = quota
 	}
 
 	preSystem, _, err = systemCPUUsage()
@@ -108,22 +104,23 @@ func initialize() error {
 		return err
 	}
 
-	preTotal, err = cpuUsage()
+	preTotal, err = cpuUsage(log)
 
 	return err
 }
 
-func cpuQuota() (float64, error) {
-	cg, err := newCGroup()
+func cpuQuota(log *slog.Logger) (float64, error) {
+	cg, err := newCGroup(log)
 	if err != nil {
 		return -1, err
 	}
 
-	return cg.cpuQuota()
+	quota, _, err := cg.cpuQuota()
+	return quota, err
 }
 
-func cpuUsage() (uint64, error) {
-	cg, err := newCGroup()
+func cpuUsage(log *slog.Logger) (uint64, error) {
+	cg, err := newCGroup(log)
 	if err != nil {
 		return 0, err
 	}
@@ -131,8 +128,8 @@ func cpuUsage() (uint64, error) {
 	return cg.cpuUsage()
 }
 
-func effectiveCpus() (int, error) {
-	cg, err := newCGroup()
+func effectiveCpus(log *slog.Logger) (int, error) {
+	cg, err := newCGroup(log)
 	if err != nil {
 		return 0, err
 	}
@@ -176,7 +173,7 @@ func systemCPUUsage() (cpuUsage uint64, cpuNum uint64, err error) {
 				}
 				totalClockTicks += v
 			}
-			cpuUsage = (totalClockTicks * uint64(time.Second)) / clockTicksPerSecond
+			cpuUsage = uint64(ticksToDuration(totalClockTicks))
 		}
 		if '0' <= line[3] && line[3] <= '9' {
 			cpuNum++
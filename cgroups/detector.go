@@ -0,0 +1,115 @@
+package cgroups
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// A Detector tracks samples and reports when a sample value, the CPU usage in
+// percent of limit by default, stays at or above a threshold for a sustained
+// period. It is safe for concurrent use.
+type Detector struct {
+	threshold float64
+	sustain   time.Duration
+	metric    string
+	value     func(Sample) float64
+
+	mu     sync.Mutex
+	log    *slog.Logger
+	since  time.Time
+	active bool
+}
+
+// NewDetector returns a Detector firing once usage has been at or above
+// threshold percent of the limit for at least sustain.
+func NewDetector(threshold float64, sustain time.Duration) *Detector {
+	return &Detector{
+		threshold: threshold,
+		sustain:   sustain,
+		metric:    "percent",
+		log:       discardLogger,
+		value: func(s Sample) float64 {
+			return s.Percent
+		},
+	}
+}
+
+// NewGCDetector returns a Detector firing once the garbage collector has
+// accounted for at least threshold percent of the container CPU usage for
+// at least sustain.
+func NewGCDetector(threshold float64, sustain time.Duration) *Detector {
+	return &Detector{
+		threshold: threshold,
+		sustain:   sustain,
+		metric:    "runtime.gc",
+		log:       discardLogger,
+		value: func(s Sample) float64 {
+			return s.Runtime.GC
+		},
+	}
+}
+
+// NewPIDsDetector returns a Detector firing once the number of tasks in the
+// cgroup has been at or above threshold percent of the pids limit for at
+// least sustain. Thread explosions often show up as CPU spikes first.
+func NewPIDsDetector(threshold float64, sustain time.Duration) *Detector {
+	return &Detector{
+		threshold: threshold,
+		sustain:   sustain,
+		metric:    "pids.percent",
+		log:       discardLogger,
+		value: func(s Sample) float64 {
+			return s.PIDs.Percent()
+		},
+	}
+}
+
+// Observe records s and reports whether it starts a high-CPU episode.
+// An episode ends as soon as a sample falls below the threshold.
+func (d *Detector) Observe(s Sample) (started bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	value := d.value(s)
+	if value < d.threshold {
+		if d.active {
+			d.log.Info("cgroup: threshold episode ended", "metric", d.metric,
+				"value", value, "threshold", d.threshold, "duration", s.Time.Sub(d.since))
+		}
+		d.since = time.Time{}
+		d.active = false
+		return false
+	}
+
+	if d.since.IsZero() {
+		d.since = s.Time
+	}
+
+	if d.active || s.Time.Sub(d.since) < d.sustain {
+		return false
+	}
+
+	d.active = true
+	d.log.Warn("cgroup: threshold episode started", "metric", d.metric,
+		"value", value, "threshold", d.threshold, "sustain", d.sustain)
+	return true
+}
+
+// SetLogger logs the start of threshold episodes at warn level and their end
+// at info level to l, with the observed metric in the metric attribute. A nil
+// logger discards the records.
+func (d *Detector) SetLogger(l *slog.Logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.log = loggerOrDiscard(l)
+}
+
+// Active reports whether a high-CPU episode is in progress.
+func (d *Detector) Active() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.active
+}
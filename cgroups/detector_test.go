@@ -0,0 +1,72 @@
+package cgroups
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectorObserve(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDetector(80, 20*time.Second)
+
+	tests := []struct {
+		offset          time.Duration
+		percent         float64
+		expectedStarted bool
+		expectedActive  bool
+	}{
+		{0, 90, false, false},
+		{10 * time.Second, 95, false, false},
+		{20 * time.Second, 85, true, true},
+		{30 * time.Second, 99, false, true},
+		{40 * time.Second, 50, false, false},
+		{50 * time.Second, 90, false, false},
+		{70 * time.Second, 90, true, true},
+	}
+
+	for _, tt := range tests {
+		started := d.Observe(Sample{Time: start.Add(tt.offset), Percent: tt.percent})
+		assert.Equal(t, tt.expectedStarted, started, "offset %s", tt.offset)
+		assert.Equal(t, tt.expectedActive, d.Active(), "offset %s", tt.offset)
+	}
+}
+
+func TestGCDetector(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewGCDetector(50, 10*time.Second)
+
+	assert.False(t, d.Observe(Sample{Time: start, Percent: 100, Runtime: RuntimeUsage{GC: 10}}))
+	assert.False(t, d.Observe(Sample{Time: start.Add(10 * time.Second), Percent: 100, Runtime: RuntimeUsage{GC: 60}}))
+	assert.True(t, d.Observe(Sample{Time: start.Add(20 * time.Second), Percent: 20, Runtime: RuntimeUsage{GC: 70}}))
+}
+
+func TestPIDsDetector(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewPIDsDetector(90, 0)
+
+	assert.False(t, d.Observe(Sample{Time: start, PIDs: PIDs{Current: 1000}}))
+	assert.False(t, d.Observe(Sample{Time: start, PIDs: PIDs{Current: 80, Limit: 100}}))
+	assert.True(t, d.Observe(Sample{Time: start, PIDs: PIDs{Current: 95, Limit: 100}}))
+}
+
+func TestDetectorLogger(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDetector(80, 10*time.Second)
+
+	var buf bytes.Buffer
+	d.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	d.Observe(Sample{Time: start, Percent: 90})
+	assert.Empty(t, buf.String())
+
+	d.Observe(Sample{Time: start.Add(10 * time.Second), Percent: 95})
+	assert.Contains(t, buf.String(), `level=WARN msg="cgroup: threshold episode started" metric=percent value=95 threshold=80 sustain=10s`)
+
+	buf.Reset()
+	d.Observe(Sample{Time: start.Add(30 * time.Second), Percent: 40})
+	assert.Contains(t, buf.String(), `level=INFO msg="cgroup: threshold episode ended" metric=percent value=40 threshold=80 duration=30s`)
+}
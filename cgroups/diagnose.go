@@ -0,0 +1,90 @@
+package cgroups
+
+import (
+	"fmt"
+	"slices"
+)
+
+// A Diagnosis explains how the cgroup of the current process was detected,
+// to find out why the reported numbers look wrong.
+type Diagnosis struct {
+	// Magic is the filesystem magic number of /sys/fs/cgroup, from statfs(2).
+	Magic int64 `json:"magic"`
+	// FSType is the filesystem Magic stands for: cgroup2, tmpfs or cgroup.
+	FSType string `json:"fs_type"`
+	// CGroups are the entries of /proc/self/cgroup, in order.
+	CGroups Subsystems `json:"cgroups"`
+	// Mounts are the raw cgroup and cgroup2 lines of /proc/self/mountinfo.
+	Mounts []string `json:"mounts"`
+	// Controllers are the v1 controllers resolved to a directory.
+	Controllers map[string]string `json:"controllers,omitempty"`
+	// Files are the files the CPU configuration and usage are read from.
+	Files []DiagnosisFile `json:"files"`
+	// Info is the detected cgroup information, nil if detection failed.
+	Info *Info `json:"info,omitempty"`
+	// Problems lists the detection failures and inconsistencies found.
+	Problems []string `json:"problems"`
+}
+
+// A DiagnosisFile is a file read during the detection, with its raw content
+// or the error reading it.
+type DiagnosisFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// fsTypeName returns the name of the cgroup related filesystem magic.
+func fsTypeName(magic int64) string {
+	switch magic {
+	case 0x63677270:
+		return "cgroup2"
+	case 0x27e0eb:
+		return "cgroup"
+	case 0x01021994:
+		return "tmpfs"
+	}
+	return fmt.Sprintf("unknown (%#x)", magic)
+}
+
+// inconsistencies returns the problems found in info.
+func inconsistencies(info Info) []string {
+	var problems []string
+
+	type requirement struct{ name, effect string }
+	var required []requirement
+	switch info.Version {
+	case 1:
+		required = []requirement{
+			{"cpu", "the quota, shares and throttling are not reported"},
+			{"cpuacct", "the usage is always 0"},
+			{"cpuset", "the limit cannot be computed"},
+		}
+	case 2:
+		// cpu.stat and the fallback to the online cpus do not depend on
+		// the controllers.
+		required = []requirement{
+			{"cpu", "the weight and throttling are not reported, enable it in the cgroup.subtree_control of the parent"},
+			{"cpuset", "the cpuset is the online cpus of the host"},
+		}
+	}
+
+	if info.Controllers != nil {
+		for _, c := range required {
+			if !slices.Contains(info.Controllers, c.name) {
+				problems = append(problems, fmt.Sprintf("v%d %s controller not available: %s", info.Version, c.name, c.effect))
+			}
+		}
+	}
+
+	if info.CPUSet == "" || info.EffectiveCPUs == 0 {
+		problems = append(problems, "cpuset is empty: the limit falls back to 0 and the percent is not computed")
+	}
+
+	if info.Quota > 0 && info.EffectiveCPUs > 0 && info.Quota > float64(info.EffectiveCPUs) {
+		problems = append(problems, fmt.Sprintf("quota of %.2f cores exceeds the %d effective cpus: the limit is the cpuset",
+			info.Quota, info.EffectiveCPUs))
+	}
+
+	return problems
+}
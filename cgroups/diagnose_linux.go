@@ -0,0 +1,130 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Diagnose reports how the cgroup of the current process is detected: the
+// filesystem mounted at /sys/fs/cgroup, the entries of /proc/self/cgroup, the
+// cgroup mounts, the files read with their raw content, and the detection
+// failures and inconsistencies found. If /proc/self/cgroup or mountinfo
+// cannot be read, the partial report is returned with the error.
+func Diagnose() (*Diagnosis, error) {
+	return diagnose(statfsMagic, selfPaths)
+}
+
+// statfsMagic returns the filesystem magic number of path.
+func statfsMagic(path string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Type), nil
+}
+
+// diagnose runs the detection from the files of p, statfs returning the
+// filesystem magic number of the cgroup mount point. It returns an error
+// along with the report when the cgroup or mountinfo file of p cannot be
+// read.
+func diagnose(statfs func(string) (int64, error), p procPaths) (*Diagnosis, error) {
+	d := &Diagnosis{}
+
+	magic, err := statfs(p.mountPoint)
+	if err != nil {
+		d.problem("statfs %s: %v", p.mountPoint, err)
+	} else {
+		d.Magic = magic
+		d.FSType = fsTypeName(d.Magic)
+	}
+
+	if d.CGroups, err = readCGroups(p.cgroup); err != nil {
+		d.problem("reading %s: %v", p.cgroup, err)
+	}
+	cgroupErr := err
+
+	mountsErr := d.readMounts(p.mountInfo)
+	if mountsErr != nil {
+		d.problem("reading %s: %v", p.mountInfo, mountsErr)
+	}
+	inputErr := errors.Join(cgroupErr, mountsErr)
+
+	unified := d.Magic == unix.CGROUP2_SUPER_MAGIC
+	if unified && d.CGroups.Unified() == nil {
+		d.problem("%s is cgroup2 but %s has no 0:: entry", p.mountPoint, p.cgroup)
+	}
+
+	cg, err := newCGroupFrom(discardLogger, unified, p)
+	if err != nil {
+		d.problem("cgroup detection failed: %v", err)
+		return d, inputErr
+	}
+
+	if v1, ok := cg.(*cgroupv1); ok {
+		d.Controllers = v1.cgroups
+	}
+
+	if _, err := os.Stat(cg.path()); err != nil {
+		d.problem("cgroup directory %s: %v", cg.path(), err)
+	}
+
+	for _, file := range cg.cpuFiles() {
+		f := DiagnosisFile{Path: file}
+		if data, err := os.ReadFile(file); err != nil {
+			f.Error = err.Error()
+		} else {
+			f.Content = strings.TrimSpace(string(data))
+		}
+		d.Files = append(d.Files, f)
+	}
+
+	info, err := cgroupSource{cg: cg, log: discardLogger}.info()
+	if err != nil {
+		d.problem("reading cgroup information: %v", err)
+		return d, inputErr
+	}
+
+	d.Info = &info
+	d.Problems = append(d.Problems, inconsistencies(info)...)
+
+	return d, inputErr
+}
+
+func (d *Diagnosis) problem(format string, args ...any) {
+	d.Problems = append(d.Problems, fmt.Sprintf(format, args...))
+}
+
+// readMounts keeps the cgroup and cgroup2 lines of the mountinfo file at path.
+func (d *Diagnosis) readMounts(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		m, err := parseMountInfoString(line)
+		if err != nil {
+			return err
+		}
+		if m.FSType == "cgroup" || m.FSType == "cgroup2" {
+			d.Mounts = append(d.Mounts, line)
+		}
+	}
+
+	if len(d.Mounts) == 0 {
+		d.problem("no cgroup filesystem mounted")
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,194 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	const (
+		cgroup2Magic = 0x63677270
+		tmpfsMagic   = 0x01021994
+	)
+
+	v1Files := map[string]string{
+		"cpu,cpuacct/cpu.cfs_quota_us":             "-1",
+		"cpu,cpuacct/docker/cpu.cfs_quota_us":      "-1",
+		"cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "150000",
+		"cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000",
+		"cpu,cpuacct/docker/abc/cpu.shares":        "1024",
+		"cpu,cpuacct/docker/abc/cpu.stat":          "nr_periods 10\nnr_throttled 2\nthrottled_time 5000",
+		"cpu,cpuacct/docker/abc/cpuacct.usage":     "123456",
+		"cpuset/docker/abc/cpuset.cpus":            "0-1",
+	}
+
+	tests := []struct {
+		name                string
+		magic               int64
+		cgroup              string
+		mountInfo           string
+		files               map[string]string
+		expectedVersion     int
+		expectedPath        string
+		expectedLimit       float64
+		expectedControllers []string
+		expectedMounts      int
+		expectedFiles       int
+		expectedProblems    []string
+	}{
+		{
+			name:   "v2",
+			magic:  cgroup2Magic,
+			cgroup: "0::/kubepods/pod/container\n",
+			mountInfo: "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+				"30 22 0:26 / %[1]s rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate\n",
+			files: map[string]string{
+				"kubepods/pod/container/cgroup.controllers":    "cpuset cpu io memory pids",
+				"kubepods/pod/container/cpu.max":               "50000 100000",
+				"kubepods/pod/container/cpu.stat":              "usage_usec 100\nnr_periods 10\nnr_throttled 2\nthrottled_usec 5",
+				"kubepods/pod/container/cpuset.cpus.effective": "0-3",
+			},
+			expectedVersion:     2,
+			expectedPath:        "kubepods/pod/container",
+			expectedLimit:       0.5,
+			expectedControllers: []string{"cpuset", "cpu", "io", "memory", "pids"},
+			expectedMounts:      1,
+			expectedFiles:       11,
+		},
+		{
+			name:   "v1",
+			magic:  tmpfsMagic,
+			cgroup: "4:cpu,cpuacct:/docker/abc\n3:cpuset:/docker/abc\n1:name=systemd:/docker/abc\n",
+			mountInfo: "33 22 0:29 / %[1]s/cpu,cpuacct rw,relatime - cgroup cgroup rw,cpu,cpuacct\n" +
+				"34 22 0:30 / %[1]s/cpuset rw,relatime - cgroup cgroup rw,cpuset\n" +
+				"41 22 0:37 / %[1]s/systemd rw,relatime - cgroup cgroup rw,name=systemd\n",
+			files:               v1Files,
+			expectedVersion:     1,
+			expectedPath:        "cpu,cpuacct/docker/abc",
+			expectedLimit:       1.5,
+			expectedControllers: []string{"cpu", "cpuacct", "cpuset", "name=systemd"},
+			expectedMounts:      3,
+			expectedFiles:       7,
+		},
+		{
+			name:   "hybrid without cpuacct",
+			magic:  tmpfsMagic,
+			cgroup: "4:cpu:/docker/abc\n3:cpuset:/docker/abc\n0::/docker/abc\n",
+			mountInfo: "33 22 0:29 / %[1]s/cpu,cpuacct rw,relatime - cgroup cgroup rw,cpu\n" +
+				"34 22 0:30 / %[1]s/cpuset rw,relatime - cgroup cgroup rw,cpuset\n" +
+				"35 22 0:31 / %[1]s/unified rw,relatime - cgroup2 cgroup2 rw\n",
+			files:               v1Files,
+			expectedVersion:     1,
+			expectedPath:        "cpu,cpuacct/docker/abc",
+			expectedLimit:       1.5,
+			expectedControllers: []string{"cpu", "cpuset"},
+			expectedMounts:      3,
+			expectedFiles:       6,
+			expectedProblems: []string{
+				"v1 cpuacct controller not available: the usage is always 0",
+			},
+		},
+		{
+			name:           "v2 outside of the mount",
+			magic:          cgroup2Magic,
+			cgroup:         "0::/kubepods/pod/container\n",
+			mountInfo:      "30 22 0:26 / %[1]s rw,relatime - cgroup2 cgroup2 rw\n",
+			expectedMounts: 1,
+			expectedProblems: []string{
+				"cgroup detection failed: open $MOUNT/kubepods/pod/container/cpu.stat: no such file or directory",
+			},
+		},
+		{
+			name:           "v2 without unified entry",
+			magic:          cgroup2Magic,
+			cgroup:         "4:cpu,cpuacct:/docker/abc\n",
+			mountInfo:      "30 22 0:26 / %[1]s rw,relatime - cgroup2 cgroup2 rw\n",
+			expectedMounts: 1,
+			expectedProblems: []string{
+				"$MOUNT is cgroup2 but $CGROUP has no 0:: entry",
+				"cgroup detection failed: cgroupv2 subsystem is nil",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writeProcFixture(t, tt.cgroup, tt.mountInfo, tt.files)
+
+			statfs := func(path string) (int64, error) {
+				assert.Equal(t, p.mountPoint, path)
+				return tt.magic, nil
+			}
+			d, err := diagnose(statfs, p)
+			require.NoError(t, err)
+
+			var expectedProblems []string
+			r := strings.NewReplacer("$MOUNT", p.mountPoint, "$CGROUP", p.cgroup)
+			for _, problem := range tt.expectedProblems {
+				expectedProblems = append(expectedProblems, r.Replace(problem))
+			}
+			assert.Equal(t, expectedProblems, d.Problems)
+			assert.Equal(t, fsTypeName(tt.magic), d.FSType)
+			assert.Len(t, d.Mounts, tt.expectedMounts)
+			assert.Len(t, d.Files, tt.expectedFiles)
+
+			if tt.expectedVersion == 0 {
+				assert.Nil(t, d.Info)
+				return
+			}
+
+			require.NotNil(t, d.Info)
+			assert.Equal(t, tt.expectedVersion, d.Info.Version)
+			assert.Equal(t, filepath.Join(p.mountPoint, tt.expectedPath), d.Info.Path)
+			assert.Equal(t, tt.expectedLimit, d.Info.Limit)
+			assert.Equal(t, tt.expectedControllers, d.Info.Controllers)
+			for _, f := range d.Files {
+				if _, exists := tt.files[f.Path[len(p.mountPoint)+1:]]; exists {
+					assert.Empty(t, f.Error, f.Path)
+					assert.NotEmpty(t, f.Content, f.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnoseStatfsError(t *testing.T) {
+	p := procPaths{
+		mountPoint: "/nonexistent/fs",
+		cgroup:     filepath.Join(testDataPath, "proc", "self", "nonexistent"),
+		mountInfo:  filepath.Join(testDataPath, "proc", "self", "mountinfo"),
+	}
+
+	d, err := diagnose(func(string) (int64, error) { return 0, os.ErrPermission }, p)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	require.NotNil(t, d)
+	assert.Equal(t, "", d.FSType)
+	assert.Contains(t, d.Problems, "statfs /nonexistent/fs: permission denied")
+	assert.Nil(t, d.Info)
+}
+
+func TestDiagnoseMountInfoError(t *testing.T) {
+	const cgroup2Magic = 0x63677270
+
+	p := writeProcFixture(t, "0::/\n", "", map[string]string{
+		"cgroup.controllers": "cpu",
+		"cpu.max":            "max 100000",
+		"cpu.stat":           "usage_usec 100",
+	})
+	p.mountInfo = filepath.Join(t.TempDir(), "nonexistent")
+
+	d, err := diagnose(func(string) (int64, error) { return cgroup2Magic, nil }, p)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	require.NotNil(t, d)
+	assert.Len(t, d.CGroups, 1)
+	assert.Contains(t, d.Problems, fmt.Sprintf("reading %s: %v", p.mountInfo, err))
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package cgroups
+
+import (
+	"errors"
+)
+
+// Diagnose reports how the cgroup of the current process is detected.
+func Diagnose() (*Diagnosis, error) {
+	return nil, errors.ErrUnsupported
+}
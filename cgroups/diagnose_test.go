@@ -0,0 +1,56 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInconsistencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     Info
+		expected []string
+	}{
+		{
+			name:     "v2 consistent",
+			info:     Info{Version: 2, Quota: 2, CPUSet: "0-3", EffectiveCPUs: 4},
+			expected: nil,
+		},
+		{
+			name:     "v1 missing cpuacct",
+			info:     Info{Version: 1, Quota: -1, CPUSet: "0-3", EffectiveCPUs: 4, Controllers: []string{"cpu", "cpuset"}},
+			expected: []string{"v1 cpuacct controller not available: the usage is always 0"},
+		},
+		{
+			name: "v2 rootless",
+			info: Info{Version: 2, Quota: -1, CPUSet: "0-3", EffectiveCPUs: 4, Controllers: []string{"memory", "pids"}},
+			expected: []string{
+				"v2 cpu controller not available: the weight and throttling are not reported, enable it in the cgroup.subtree_control of the parent",
+				"v2 cpuset controller not available: the cpuset is the online cpus of the host",
+			},
+		},
+		{
+			name:     "empty cpuset",
+			info:     Info{Version: 2, Quota: -1},
+			expected: []string{"cpuset is empty: the limit falls back to 0 and the percent is not computed"},
+		},
+		{
+			name:     "quota above cpus",
+			info:     Info{Version: 2, Quota: 8, CPUSet: "0-3", EffectiveCPUs: 4},
+			expected: []string{"quota of 8.00 cores exceeds the 4 effective cpus: the limit is the cpuset"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, inconsistencies(tt.info))
+		})
+	}
+}
+
+func TestFSTypeName(t *testing.T) {
+	assert.Equal(t, "cgroup2", fsTypeName(0x63677270))
+	assert.Equal(t, "tmpfs", fsTypeName(0x01021994))
+	assert.Equal(t, "unknown (0x1234)", fsTypeName(0x1234))
+}
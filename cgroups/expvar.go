@@ -0,0 +1,76 @@
+package cgroups
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// PublishExpvar publishes the statistics of c as an expvar.Map under name,
+// served by the /debug/vars handler of the expvar package. Like
+// expvar.Publish, it panics if name is already registered: expvar cannot
+// unpublish a variable, and the map stays registered with c for the lifetime
+// of the process, so call it once per name, e.g. from main.
+//
+// PublishExpvar does not sample: the map is updated on every sample collected
+// by c, so the caller must run c, typically with go c.Run(ctx, interval).
+// Until then only the cgroup information is published.
+//
+// The map holds the latest sample (usage, percent, process_usage,
+// throttled_periods, throttled_time_ns) and the cgroup information
+// (cgroup_version, limit, quota, effective_cpus).
+func PublishExpvar(name string, c *Collector) *expvar.Map {
+	if expvar.Get(name) != nil {
+		panic(fmt.Sprintf("cgroups: expvar %q already published", name))
+	}
+	m := expvar.NewMap(name)
+
+	info := c.Info()
+	setInt(m, "cgroup_version", int64(info.Version))
+	setFloat(m, "limit", info.Limit)
+	setFloat(m, "quota", info.Quota)
+	setInt(m, "effective_cpus", int64(info.EffectiveCPUs))
+
+	var (
+		mu      sync.Mutex
+		updated bool
+	)
+	set := func(s Sample) {
+		setFloat(m, "usage", s.Usage)
+		setFloat(m, "percent", s.Percent)
+		setFloat(m, "process_usage", s.ProcessUsage)
+		setInt(m, "throttled_periods", int64(s.Throttling.ThrottledPeriods))
+		setInt(m, "throttled_time_ns", int64(s.Throttling.ThrottledTime))
+	}
+	update := func(s Sample) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		updated = true
+		set(s)
+	}
+
+	// The latest sample is read when update is registered, so no sample is
+	// missed. It is older than any sample already passed to update.
+	if s, ok := c.onSampleLatest(update); ok {
+		mu.Lock()
+		if !updated {
+			set(s)
+		}
+		mu.Unlock()
+	}
+
+	return m
+}
+
+func setFloat(m *expvar.Map, key string, value float64) {
+	v := new(expvar.Float)
+	v.Set(value)
+	m.Set(key, v)
+}
+
+func setInt(m *expvar.Map, key string, value int64) {
+	v := new(expvar.Int)
+	v.Set(value)
+	m.Set(key, v)
+}
@@ -0,0 +1,55 @@
+package cgroups
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	c, err := newCollector(newFakeSource(0, 1e9, 3e9))
+	require.NoError(t, err)
+
+	m := PublishExpvar("container_cpu_test", c)
+	assert.Same(t, m, expvar.Get("container_cpu_test"))
+
+	values := func() map[string]float64 {
+		var out map[string]float64
+		require.NoError(t, json.Unmarshal([]byte(m.String()), &out))
+		return out
+	}
+
+	assert.Equal(t, map[string]float64{
+		"cgroup_version": 2,
+		"limit":          2,
+		"quota":          2,
+		"effective_cpus": 4,
+	}, values())
+
+	_, err = c.Collect()
+	require.NoError(t, err)
+	_, err = c.Collect()
+	require.NoError(t, err)
+
+	v := values()
+	assert.InDelta(t, 2.0, v["usage"], 1e-9)
+	assert.InDelta(t, 100.0, v["percent"], 1e-9)
+}
+
+func TestPublishExpvarLatest(t *testing.T) {
+	c, err := newCollector(newFakeSource(0, 1e9, 3e9))
+	require.NoError(t, err)
+
+	_, err = c.Collect()
+	require.NoError(t, err)
+
+	m := PublishExpvar("container_cpu_test_latest", c)
+	assert.Equal(t, "1", m.Get("usage").String())
+
+	assert.PanicsWithValue(t, `cgroups: expvar "container_cpu_test_latest" already published`, func() {
+		PublishExpvar("container_cpu_test_latest", c)
+	})
+}
@@ -0,0 +1,105 @@
+package cgroups
+
+import (
+	"runtime/metrics"
+	"time"
+)
+
+// runtimeCPUMetrics are the runtime/metrics estimates of the CPU time spent
+// by the Go runtime, in the order of the RuntimeCPU fields.
+var runtimeCPUMetrics = []string{
+	"/cpu/classes/gc/total:cpu-seconds",
+	"/cpu/classes/scavenge/total:cpu-seconds",
+	"/cpu/classes/user/total:cpu-seconds",
+	"/cpu/classes/idle/total:cpu-seconds",
+	"/cpu/classes/total:cpu-seconds",
+}
+
+// RuntimeCPU is the cumulative CPU time of the Go runtime, broken down by
+// class as estimated by runtime/metrics.
+// https://pkg.go.dev/runtime/metrics#hdr-Supported_metrics
+type RuntimeCPU struct {
+	GC       time.Duration `json:"gc"`
+	Scavenge time.Duration `json:"scavenge"`
+	User     time.Duration `json:"user"`
+	Idle     time.Duration `json:"idle"`
+	Total    time.Duration `json:"total"`
+}
+
+// ReadRuntimeCPU returns the current Go runtime CPU breakdown.
+func ReadRuntimeCPU() RuntimeCPU {
+	samples := make([]metrics.Sample, len(runtimeCPUMetrics))
+	for i, name := range runtimeCPUMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	values := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		if s.Value.Kind() == metrics.KindFloat64 {
+			values[i] = time.Duration(s.Value.Float64() * float64(time.Second))
+		}
+	}
+
+	return RuntimeCPU{
+		GC:       values[0],
+		Scavenge: values[1],
+		User:     values[2],
+		Idle:     values[3],
+		Total:    values[4],
+	}
+}
+
+// RuntimeUsage is the Go runtime CPU breakdown, each class expressed as a
+// percent of the container CPU usage.
+//
+// The runtime/metrics CPU classes are estimates that the runtime only
+// refreshes when a garbage collection runs, so their delta over a sampling
+// interval is mostly zero with sudden spikes. RuntimeUsage is instead taken
+// over the window between the last two refreshes, against the container CPU
+// usage between the samples that observed them, and is carried over by the
+// samples in between. It is an estimate accurate to a sampling interval at
+// each end of the window, capped at 100.
+type RuntimeUsage struct {
+	GC       float64 `json:"gc"`
+	Scavenge float64 `json:"scavenge"`
+	User     float64 `json:"user"`
+}
+
+// runtimeUsage returns the classes of cur accumulated since prev as a percent
+// of cpuDelta, the container CPU time in nanoseconds over the same window.
+func runtimeUsage(cur, prev RuntimeCPU, cpuDelta float64) RuntimeUsage {
+	if cpuDelta <= 0 {
+		return RuntimeUsage{}
+	}
+
+	percent := func(cur, prev time.Duration) float64 {
+		return min(float64(max(cur-prev, 0))*100/cpuDelta, 100)
+	}
+
+	return RuntimeUsage{
+		GC:       percent(cur.GC, prev.GC),
+		Scavenge: percent(cur.Scavenge, prev.Scavenge),
+		User:     percent(cur.User, prev.User),
+	}
+}
+
+// runtimeWindow is the start of the window RuntimeUsage is computed over:
+// the runtime CPU classes at their last refresh and the container CPU time
+// of the sample that observed it.
+type runtimeWindow struct {
+	runtime RuntimeCPU
+	total   uint64
+}
+
+// advance returns the usage over the window ending at r and the window
+// starting there, or ok false if the runtime classes were not refreshed
+// since w.
+func (w runtimeWindow) advance(r reading) (usage RuntimeUsage, next runtimeWindow, ok bool) {
+	if r.runtime == w.runtime {
+		return RuntimeUsage{}, w, false
+	}
+
+	cpuDelta := float64(r.total) - float64(w.total)
+	return runtimeUsage(r.runtime, w.runtime, cpuDelta), runtimeWindow{runtime: r.runtime, total: r.total}, true
+}
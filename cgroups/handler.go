@@ -0,0 +1,59 @@
+package cgroups
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// debugResponse is the JSON document served by the debug handler.
+type debugResponse struct {
+	Sample  *Sample  `json:"sample,omitempty"`
+	Info    Info     `json:"info"`
+	History []Sample `json:"history"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// NewHandler returns an http.Handler serving the latest sample, the cgroup
+// information and the sample history of c as JSON. It is meant to be mounted
+// next to net/http/pprof, e.g. under /debug/cpu.
+//
+// The optional `history` query parameter limits the number of samples
+// returned, most recent last. The handler never collects a sample itself:
+// until the Collector has collected one, e.g. with Run, it responds with
+// 503 Service Unavailable and the cgroup information only.
+func NewHandler(c *Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := -1
+		if v := r.URL.Query().Get("history"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid history parameter: "+v, http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		resp := debugResponse{
+			Info: c.Info(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		sample, ok := c.Latest()
+		if !ok {
+			resp.Error = "no sample collected yet"
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		resp.Sample = &sample
+		resp.History = c.History()
+		if limit >= 0 && limit < len(resp.History) {
+			resp.History = resp.History[len(resp.History)-limit:]
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
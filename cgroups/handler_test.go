@@ -0,0 +1,68 @@
+package cgroups
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	c, err := newCollector(newFakeSource(0, 1, 2, 3))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Collect()
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedLen    int
+	}{
+		{name: "all", query: "", expectedStatus: http.StatusOK, expectedLen: 2},
+		{name: "limited", query: "?history=1", expectedStatus: http.StatusOK, expectedLen: 1},
+		{name: "none", query: "?history=0", expectedStatus: http.StatusOK, expectedLen: 0},
+		{name: "invalid", query: "?history=x", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		NewHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/cpu"+tt.query, nil))
+		assert.Equal(t, tt.expectedStatus, rec.Code, tt.name)
+		if tt.expectedStatus != http.StatusOK {
+			continue
+		}
+
+		var resp debugResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp), tt.name)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), tt.name)
+		assert.Equal(t, c.Info(), resp.Info, tt.name)
+		assert.NotNil(t, resp.Sample, tt.name)
+		assert.Len(t, resp.History, tt.expectedLen, tt.name)
+	}
+}
+
+func TestHandlerNoSample(t *testing.T) {
+	c, err := newCollector(newFakeSource(0, 1))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	NewHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/cpu", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var resp debugResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, c.Info(), resp.Info)
+	assert.Nil(t, resp.Sample)
+	assert.NotEmpty(t, resp.Error)
+
+	// Serving the request does not collect.
+	_, ok := c.Latest()
+	assert.False(t, ok)
+}
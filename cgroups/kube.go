@@ -0,0 +1,181 @@
+package cgroups
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotKubernetes is returned when the cgroup of the current process is not
+// part of a Kubernetes pod hierarchy.
+var ErrNotKubernetes = errors.New("cgroups: not running in a Kubernetes pod cgroup")
+
+// QoSClass is the Kubernetes quality of service class of a pod.
+type QoSClass string
+
+const (
+	QoSGuaranteed QoSClass = "Guaranteed"
+	QoSBurstable  QoSClass = "Burstable"
+	QoSBestEffort QoSClass = "BestEffort"
+)
+
+// KubeCPU is the CPU configuration of a Kubernetes pod or container cgroup,
+// with the resources it was likely created from.
+type KubeCPU struct {
+	Path string `json:"path"`
+	// Quota is the CPU quota in cores, -1 if unlimited.
+	Quota  float64   `json:"quota"`
+	Weight CPUWeight `json:"weight"`
+	// RequestMillis is the CPU request in millicores, derived from the
+	// shares or weight. It is approximate as the conversion is lossy.
+	RequestMillis int64 `json:"request_millis"`
+	// LimitMillis is the CPU limit in millicores, 0 if unlimited.
+	LimitMillis int64 `json:"limit_millis"`
+}
+
+// KubeResources describes the Kubernetes pod the current container
+// belongs to, as seen from the cgroup hierarchy.
+type KubeResources struct {
+	QoS       QoSClass `json:"qos"`
+	PodUID    string   `json:"pod_uid"`
+	Pod       KubeCPU  `json:"pod"`
+	Container KubeCPU  `json:"container"`
+}
+
+const (
+	// minShares is the cpu.shares the kubelet sets for pods without a
+	// CPU request.
+	minShares = 2
+	maxShares = 262144
+)
+
+// sharesToMillicores inverts the kubelet conversion of a CPU request to
+// cpu.shares, `shares = millicores * 1024 / 1000`.
+func sharesToMillicores(shares uint64) int64 {
+	if shares <= minShares {
+		return 0
+	}
+	return int64((shares*1000 + 512) / 1024)
+}
+
+// weightToMillicores inverts the conversion of cpu.shares to cpu.weight
+// used by container runtimes on cgroup v2,
+// `weight = 1 + ((shares - 2) * 9999) / 262142`. Each weight covers a range
+// of about 26 shares, whose midpoint is returned.
+func weightToMillicores(weight uint64) int64 {
+	if weight <= 1 {
+		return 0
+	}
+	shares := minShares + ((2*weight-1)*(maxShares-minShares))/(2*9999)
+	return sharesToMillicores(shares)
+}
+
+// parseKubePodPath finds the pod cgroup in the cgroup path p, for both the
+// cgroupfs (`/kubepods/burstable/pod<uid>/<container>`) and systemd
+// (`/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice/<container>.scope`)
+// drivers. Guaranteed pods are placed directly under kubepods.
+func parseKubePodPath(p string) (podPath string, podUID string, qos QoSClass, ok bool) {
+	segments := strings.Split(filepath.Clean(p), "/")
+
+	inKubepods := false
+	qos = QoSGuaranteed
+	for i, segment := range segments {
+		name := strings.TrimSuffix(segment, ".slice")
+		if strings.HasPrefix(name, "kubepods") {
+			inKubepods = true
+		}
+		if !inKubepods {
+			continue
+		}
+
+		switch {
+		case strings.Contains(name, "burstable"):
+			qos = QoSBurstable
+		case strings.Contains(name, "besteffort"):
+			qos = QoSBestEffort
+		}
+
+		// The systemd driver prefixes the pod slice with its parents and
+		// replaces the dashes of the uid, e.g. kubepods-burstable-pod<uid>.
+		uid, found := strings.CutPrefix(name, "pod")
+		if !found {
+			if i := strings.LastIndex(name, "-pod"); i >= 0 {
+				uid, found = name[i+len("-pod"):], true
+			}
+		}
+		if !found || uid == "" {
+			continue
+		}
+
+		podUID = strings.ReplaceAll(uid, "_", "-")
+		return strings.Join(segments[:i+1], "/"), podUID, qos, true
+	}
+
+	return "", "", "", false
+}
+
+// idPaths returns the paths the container and pod IDs are looked for in:
+// CGroup, which keeps them when the hierarchy is mounted from inside the
+// container, then Path.
+func (i Info) idPaths() []string {
+	return []string{i.CGroup, i.Path}
+}
+
+// PodUID returns the uid of the Kubernetes pod from the cgroup path, or an
+// empty string outside of a pod hierarchy.
+func (i Info) PodUID() string {
+	for _, p := range i.idPaths() {
+		if _, uid, _, ok := parseKubePodPath(p); ok {
+			return uid
+		}
+	}
+	return ""
+}
+
+// ContainerID returns the 64 hexadecimal characters container ID found in
+// the last segment of the cgroup path, e.g. `docker-<id>.scope` or
+// `cri-containerd-<id>.scope`, or an empty string.
+func (i Info) ContainerID() string {
+	for _, p := range i.idPaths() {
+		if id := parseContainerID(p); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// parseContainerID returns the container ID in the last segment of p.
+func parseContainerID(p string) string {
+	name := filepath.Base(p)
+	name = strings.TrimSuffix(name, ".scope")
+	if j := strings.LastIndexAny(name, "-:"); j >= 0 {
+		name = name[j+1:]
+	}
+
+	if len(name) != 64 {
+		return ""
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return ""
+		}
+	}
+
+	return name
+}
+
+// kubeCPU derives the requests and limits of c from its quota and weight.
+func kubeCPU(c KubeCPU) KubeCPU {
+	if c.Quota > 0 {
+		c.LimitMillis = int64(c.Quota*1000 + 0.5)
+	}
+
+	switch {
+	case c.Weight.Shares > 0:
+		c.RequestMillis = sharesToMillicores(c.Weight.Shares)
+	case c.Weight.Weight > 0:
+		c.RequestMillis = weightToMillicores(c.Weight.Weight)
+	}
+
+	return c
+}
@@ -0,0 +1,110 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"path"
+	"strings"
+)
+
+// KubernetesResources returns the QoS class of the pod the current process
+// runs in, with the CPU configuration of the pod and container cgroups, or
+// ErrNotKubernetes. The pod is detected from the path in /proc/self/cgroup,
+// which does not show it with a private cgroup namespace. When the pod
+// cgroup is not visible, e.g. the hierarchy is mounted from inside the
+// container, only Container is set.
+func KubernetesResources() (*KubeResources, error) {
+	cg, err := newCGroup(defaultLogger())
+	if err != nil {
+		return nil, err
+	}
+
+	return kubeResources(cg)
+}
+
+func kubeResources(cg cgroup) (*KubeResources, error) {
+	podName, podUID, qos, ok := parseKubePodPath(cg.name())
+	if !ok {
+		return nil, ErrNotKubernetes
+	}
+
+	containerPath := cg.path()
+	container, err := readKubeCPU(cg, containerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &KubeResources{
+		QoS:       qos,
+		PodUID:    podUID,
+		Container: container,
+	}
+
+	podPath, ok := kubePodDir(cg, podName)
+	switch {
+	case !ok:
+		// The pod cgroup is not visible, only the container is reported.
+	case podPath == containerPath:
+		res.Pod = container
+	default:
+		if res.Pod, err = readKubeCPU(cg, podPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// kubePodDir returns the directory of the pod cgroup podName, the ancestor
+// of the cgroup of cg in /proc/self/cgroup. ok is false if it is above the
+// mount point, i.e. not visible from the container.
+func kubePodDir(cg cgroup, podName string) (dir string, ok bool) {
+	var mount string
+	switch cg := cg.(type) {
+	case *cgroupv2:
+		mount = cg.mount
+	case *cgroupv1:
+		mount = cg.mounts["cpu"]
+	}
+
+	rel := strings.TrimPrefix(path.Clean(cg.name()), podName)
+	dir, found := strings.CutSuffix(cg.path(), rel)
+	if !found || !isWithin(dir, mount) {
+		return "", false
+	}
+
+	return dir, true
+}
+
+// readKubeCPU reads the own quota and weight of the cgroup at dir, in the
+// hierarchy of cg.
+func readKubeCPU(cg cgroup, dir string) (KubeCPU, error) {
+	var (
+		c      = KubeCPU{Path: dir}
+		weight CPUWeight
+		err    error
+	)
+
+	switch cg := cg.(type) {
+	case *cgroupv2:
+		if c.Quota, err = readCPUMax(dir); err != nil {
+			return KubeCPU{}, err
+		}
+		weight, err = (&cgroupv2{dir: dir, mount: cg.mount}).cpuWeight()
+	case *cgroupv1:
+		if c.Quota, err = readCFSQuota(dir); err != nil {
+			return KubeCPU{}, err
+		}
+		weight, err = (&cgroupv1{
+			cgroups: map[string]string{"cpu": dir},
+			mounts:  cg.mounts,
+		}).cpuWeight()
+	}
+	if err != nil {
+		return KubeCPU{}, err
+	}
+
+	c.Weight = weight
+	return kubeCPU(c), nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package cgroups
+
+import (
+	"errors"
+)
+
+// KubernetesResources returns the QoS class of the pod the current process
+// runs in, with the CPU configuration of the pod and container cgroups.
+func KubernetesResources() (*KubeResources, error) {
+	return nil, errors.ErrUnsupported
+}
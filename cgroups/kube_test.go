@@ -0,0 +1,102 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKubePodPath(t *testing.T) {
+	testTable := []struct {
+		name            string
+		path            string
+		expectedPodPath string
+		expectedUID     string
+		expectedQoS     QoSClass
+		expectedOK      bool
+	}{
+		{
+			name:            "cgroupfs-burstable",
+			path:            "/sys/fs/cgroup/cpu/kubepods/burstable/pod0a1b2c3d-1111-2222-3333-444455556666/1753b7cbbf62",
+			expectedPodPath: "/sys/fs/cgroup/cpu/kubepods/burstable/pod0a1b2c3d-1111-2222-3333-444455556666",
+			expectedUID:     "0a1b2c3d-1111-2222-3333-444455556666",
+			expectedQoS:     QoSBurstable,
+			expectedOK:      true,
+		},
+		{
+			name:            "cgroupfs-guaranteed",
+			path:            "/kubepods/pod0a1b2c3d/1753b7cbbf62",
+			expectedPodPath: "/kubepods/pod0a1b2c3d",
+			expectedUID:     "0a1b2c3d",
+			expectedQoS:     QoSGuaranteed,
+			expectedOK:      true,
+		},
+		{
+			name:            "systemd-besteffort",
+			path:            "/sys/fs/cgroup/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-podb41662f7_b03a_4c65.slice/cri-containerd-1753b7cbbf62.scope",
+			expectedPodPath: "/sys/fs/cgroup/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-podb41662f7_b03a_4c65.slice",
+			expectedUID:     "b41662f7-b03a-4c65",
+			expectedQoS:     QoSBestEffort,
+			expectedOK:      true,
+		},
+		{
+			name: "docker",
+			path: "/sys/fs/cgroup/system.slice/docker-1753b7cbbf62.scope",
+		},
+		{
+			name: "kubepods-only",
+			path: "/kubepods/burstable",
+		},
+	}
+
+	for _, tt := range testTable {
+		podPath, uid, qos, ok := parseKubePodPath(tt.path)
+		assert.Equal(t, tt.expectedOK, ok, tt.name)
+		assert.Equal(t, tt.expectedPodPath, podPath, tt.name)
+		assert.Equal(t, tt.expectedUID, uid, tt.name)
+		assert.Equal(t, tt.expectedQoS, qos, tt.name)
+	}
+}
+
+func TestKubeCPU(t *testing.T) {
+	tests := []struct {
+		input           KubeCPU
+		expectedRequest int64
+		expectedLimit   int64
+	}{
+		{KubeCPU{Quota: -1, Weight: CPUWeight{Shares: 2}}, 0, 0},
+		{KubeCPU{Quota: 1.5, Weight: CPUWeight{Shares: 512}}, 500, 1500},
+		{KubeCPU{Quota: 0.25, Weight: CPUWeight{Shares: 102}}, 100, 250},
+		{KubeCPU{Quota: -1, Weight: CPUWeight{Weight: 1}}, 0, 0},
+		{KubeCPU{Quota: 2, Weight: CPUWeight{Weight: 79}}, 2000, 2000},
+	}
+
+	for _, tt := range tests {
+		kube := kubeCPU(tt.input)
+		assert.InDelta(t, tt.expectedRequest, kube.RequestMillis, 15, "%+v", tt.input)
+		assert.Equal(t, tt.expectedLimit, kube.LimitMillis, "%+v", tt.input)
+	}
+}
+
+func TestInfoContainerID(t *testing.T) {
+	id := "1753b7cbbf62734d812936961224d5bc0cf8f45214e0d5cdd1a781a053e7c48f"
+
+	tests := []struct {
+		path        string
+		expectedID  string
+		expectedUID string
+	}{
+		{"/sys/fs/cgroup/cpu/docker/" + id, id, ""},
+		{"/sys/fs/cgroup/system.slice/docker-" + id + ".scope", id, ""},
+		{"/sys/fs/cgroup/kubepods.slice/kubepods-pod1234_abcd.slice/cri-containerd-" + id + ".scope", id, "1234-abcd"},
+		{"/sys/fs/cgroup/kubepods/burstable/pod1234-abcd/" + id, id, "1234-abcd"},
+		{"/sys/fs/cgroup", "", ""},
+		{"/sys/fs/cgroup/user.slice/user-1000.slice", "", ""},
+	}
+
+	for _, tt := range tests {
+		info := Info{Path: tt.path}
+		assert.Equal(t, tt.expectedID, info.ContainerID(), tt.path)
+		assert.Equal(t, tt.expectedUID, info.PodUID(), tt.path)
+	}
+}
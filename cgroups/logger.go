@@ -0,0 +1,39 @@
+package cgroups
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+var pkgLogger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger used by CollectCPUUsage, KubernetesResources and
+// the cgroup discovery. Detection decisions are logged at debug level and
+// initialization failures at warn level. A nil logger discards the records,
+// which is the default.
+func SetLogger(l *slog.Logger) {
+	pkgLogger.Store(l)
+}
+
+// defaultLogger returns the logger set with SetLogger, or a discarding one.
+func defaultLogger() *slog.Logger {
+	return loggerOrDiscard(pkgLogger.Load())
+}
+
+func loggerOrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return discardLogger
+	}
+	return l
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler drops every record. slog.DiscardHandler requires Go 1.24.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
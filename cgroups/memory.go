@@ -0,0 +1,21 @@
+package cgroups
+
+// Memory describes the memory controller statistics of the cgroup.
+type Memory struct {
+	// Usage is the memory currently charged to the cgroup, in bytes.
+	Usage uint64 `json:"usage"`
+	// Limit is the hard memory limit in bytes, 0 if unlimited.
+	Limit uint64 `json:"limit"`
+	// Stat holds the memory.stat counters.
+	Stat map[string]uint64 `json:"stat,omitempty"`
+	// OOM is the number of times the cgroup hit its limit and the OOM
+	// killer was considered (v2 only).
+	OOM uint64 `json:"oom"`
+	// OOMKill is the number of processes killed by the OOM killer.
+	OOMKill uint64 `json:"oom_kill"`
+}
+
+// memoryUnlimited is the threshold above which a cgroup v1 memory limit is
+// considered unset; the kernel reports PAGE_COUNTER_MAX rounded to the page
+// size, e.g. 9223372036854771712.
+const memoryUnlimited = 1 << 62
@@ -1,19 +1,19 @@
-//go:build linux
-// +build linux
-
 package cgroups
 
 import (
 	"bufio"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 )
 
+const procMountInfoPath = "/proc/self/mountinfo"
+
 // FilterFunc used to filter out mountinfo entries
 // skip: true if the entry should be skipped
-type filterFunc func(*MountInfo) (skip bool)
+type FilterFunc func(*MountInfo) (skip bool)
 
 // A MountInfo is a type that describes the details, options
 // for each mount, parsed from /proc/self/mountinfo.
@@ -60,12 +60,12 @@ func parseMountInfoString(mountString string) (*MountInfo, error) {
 		MountID:        mountID,
 		ParentID:       parentID,
 		MajorMinorVer:  fields[2],
-		Root:           fields[3],
-		MountPoint:     fields[4],
+		Root:           unescapeMountInfo(fields[3]),
+		MountPoint:     unescapeMountInfo(fields[4]),
 		Options:        strings.Split(fields[5], ","),
 		OptionalFields: nil,
 		FSType:         fields[numFields-3],
-		MountSource:    fields[numFields-2],
+		MountSource:    unescapeMountInfo(fields[numFields-2]),
 		SuperOptions:   strings.Split(fields[numFields-1], ","),
 	}
 
@@ -77,8 +77,40 @@ func parseMountInfoString(mountString string) (*MountInfo, error) {
 	return mount, nil
 }
 
-// getMounts retrieves mountinfo information from `/proc/self/mountinfo`.
-func getMountInfos(path string, filter filterFunc) ([]*MountInfo, error) {
+// unescapeMountInfo decodes the octal escapes the kernel uses in the paths
+// of mountinfo for space (\040), tab (\011), newline (\012) and backslash
+// (\134). Invalid escapes are kept as is.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// GetMountInfos returns the entries of `/proc/self/mountinfo` kept by all of
+// filters.
+func GetMountInfos(filters ...FilterFunc) ([]*MountInfo, error) {
+	return getMountInfos(procMountInfoPath, filters...)
+}
+
+// getMountInfos retrieves mountinfo information from path.
+func getMountInfos(path string, filters ...FilterFunc) ([]*MountInfo, error) {
 	mountInfoFile, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -94,11 +126,8 @@ func getMountInfos(path string, filter filterFunc) ([]*MountInfo, error) {
 			return nil, err
 		}
 
-		if filter != nil {
-			skip := filter(parsedMounts)
-			if skip {
-				continue
-			}
+		if skip(parsedMounts, filters) {
+			continue
 		}
 
 		mounts = append(mounts, parsedMounts)
@@ -108,8 +137,18 @@ func getMountInfos(path string, filter filterFunc) ([]*MountInfo, error) {
 	return mounts, err
 }
 
-// fsTypeFilter returns all entries that match provided fstype(s).
-func fsTypeFilter(fstype ...string) filterFunc {
+// skip reports whether any of filters skips m.
+func skip(m *MountInfo, filters []FilterFunc) bool {
+	for _, filter := range filters {
+		if filter != nil && filter(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// FSTypeFilter returns all entries that match provided fstype(s).
+func FSTypeFilter(fstype ...string) FilterFunc {
 	return func(m *MountInfo) bool {
 		for _, t := range fstype {
 			if m.FSType == t {
@@ -119,3 +158,51 @@ func fsTypeFilter(fstype ...string) filterFunc {
 		return true // skip
 	}
 }
+
+// MountPointFilter returns the entries mounted at mountPoint.
+func MountPointFilter(mountPoint string) FilterFunc {
+	return func(m *MountInfo) bool {
+		return m.MountPoint != mountPoint
+	}
+}
+
+// SuperOptionFilter returns the entries with the super option opt, e.g.
+// `cpu` or `name=systemd` for cgroup v1 hierarchies.
+func SuperOptionFilter(opt string) FilterFunc {
+	return func(m *MountInfo) bool {
+		for _, o := range m.SuperOptions {
+			if o == opt {
+				return false // don't skip
+			}
+		}
+		return true // skip
+	}
+}
+
+// resolveCGroupDir returns the directory of the cgroup at cgroupPath, as
+// listed in /proc/self/cgroup, in the hierarchy mounted by m.
+//
+// Both cgroupPath and the mount Root are relative to the root of the cgroup
+// namespace of the process. On the host Root is `/` and the directory is
+// cgroupPath below the mount point. In a container with a private namespace
+// cgroupPath is `/` and so is Root when the hierarchy is mounted inside the
+// namespace. When the host hierarchy is bind mounted instead, Root is the
+// cgroup of the container, or of one of its ancestors in nested containers,
+// and only the part of cgroupPath below Root is kept.
+//
+// ok is false when cgroupPath is outside of Root, e.g. when Root is `/..`
+// because the mount predates the namespace, in which case the mount point is
+// returned.
+func resolveCGroupDir(cgroupPath string, m *MountInfo) (dir string, ok bool) {
+	// path.Clean resolves `/..` to `/`.
+	if m.Root == "/.." || strings.HasPrefix(m.Root, "/../") {
+		return m.MountPoint, false
+	}
+
+	root, cgroupPath := path.Clean(m.Root), path.Clean(cgroupPath)
+	if !isWithin(cgroupPath, root) {
+		return m.MountPoint, false
+	}
+
+	return path.Join(m.MountPoint, strings.TrimPrefix(cgroupPath, root)), true
+}
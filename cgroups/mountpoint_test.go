@@ -0,0 +1,175 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnescapeMountInfo(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/sys/fs/cgroup`, `/sys/fs/cgroup`},
+		{`/mnt/my\040data`, "/mnt/my data"},
+		{`/a\011b\012c`, "/a\tb\nc"},
+		{`back\134slash`, `back\slash`},
+		{`trailing\04`, `trailing\04`},
+		{`not\999octal`, `not\999octal`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, unescapeMountInfo(tt.input), tt.input)
+	}
+}
+
+func TestParseMountInfoString(t *testing.T) {
+	m, err := parseMountInfoString(`50 22 0:45 /my\040volume /mnt/my\040data rw,relatime shared:5 master:1 - tmpfs back\134slash rw,size=64k`)
+	require.NoError(t, err)
+	assert.Equal(t, &MountInfo{
+		MountID:        50,
+		ParentID:       22,
+		MajorMinorVer:  "0:45",
+		Root:           "/my volume",
+		MountPoint:     "/mnt/my data",
+		Options:        []string{"rw", "relatime"},
+		OptionalFields: []string{"shared:5", "master:1"},
+		FSType:         "tmpfs",
+		MountSource:    `back\slash`,
+		SuperOptions:   []string{"rw", "size=64k"},
+	}, m)
+
+	_, err = parseMountInfoString("22 1 8:1 / / rw")
+	assert.Error(t, err)
+}
+
+func TestGetMountInfos(t *testing.T) {
+	const path = "testdata/proc/self/mountinfo"
+
+	tests := []struct {
+		name     string
+		filters  []FilterFunc
+		expected []string
+	}{
+		{
+			name:     "all",
+			expected: []string{"/", "/sys/fs/cgroup", "/sys/fs/cgroup/cpu,cpuacct", "/sys/fs/cgroup/systemd", "/mnt/my data\ttab"},
+		},
+		{
+			name:     "fstype",
+			filters:  []FilterFunc{FSTypeFilter("cgroup", "cgroup2")},
+			expected: []string{"/sys/fs/cgroup", "/sys/fs/cgroup/cpu,cpuacct", "/sys/fs/cgroup/systemd"},
+		},
+		{
+			name:     "mount point",
+			filters:  []FilterFunc{MountPointFilter("/mnt/my data\ttab")},
+			expected: []string{"/mnt/my data\ttab"},
+		},
+		{
+			name:     "super option",
+			filters:  []FilterFunc{FSTypeFilter("cgroup"), SuperOptionFilter("cpuacct")},
+			expected: []string{"/sys/fs/cgroup/cpu,cpuacct"},
+		},
+		{
+			name:     "named hierarchy",
+			filters:  []FilterFunc{SuperOptionFilter("name=systemd")},
+			expected: []string{"/sys/fs/cgroup/systemd"},
+		},
+		{
+			name:     "no match",
+			filters:  []FilterFunc{FSTypeFilter("cgroup2"), SuperOptionFilter("cpu")},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mounts, err := getMountInfos(path, tt.filters...)
+			require.NoError(t, err)
+
+			var mountPoints []string
+			for _, m := range mounts {
+				mountPoints = append(mountPoints, m.MountPoint)
+			}
+			assert.Equal(t, tt.expected, mountPoints)
+		})
+	}
+}
+
+func TestResolveCGroupDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		cgroupPath string
+		root       string
+		mountPoint string
+		expected   string
+		expectedOK bool
+	}{
+		{
+			name:       "host",
+			cgroupPath: "/system.slice/app.service",
+			root:       "/",
+			mountPoint: "/sys/fs/cgroup",
+			expected:   "/sys/fs/cgroup/system.slice/app.service",
+			expectedOK: true,
+		},
+		{
+			name:       "private namespace",
+			cgroupPath: "/",
+			root:       "/",
+			mountPoint: "/sys/fs/cgroup",
+			expected:   "/sys/fs/cgroup",
+			expectedOK: true,
+		},
+		{
+			name:       "host namespace with bind mounted cgroup",
+			cgroupPath: "/docker/abc",
+			root:       "/docker/abc",
+			mountPoint: "/sys/fs/cgroup/cpu,cpuacct",
+			expected:   "/sys/fs/cgroup/cpu,cpuacct",
+			expectedOK: true,
+		},
+		{
+			name:       "nested container",
+			cgroupPath: "/docker/outer/docker/inner",
+			root:       "/docker/outer",
+			mountPoint: "/sys/fs/cgroup",
+			expected:   "/sys/fs/cgroup/docker/inner",
+			expectedOK: true,
+		},
+		{
+			name:       "kind node in private namespace",
+			cgroupPath: "/kubelet.slice/kubelet-kubepods.slice/pod.slice/cri-containerd-abc.scope",
+			root:       "/",
+			mountPoint: "/sys/fs/cgroup",
+			expected:   "/sys/fs/cgroup/kubelet.slice/kubelet-kubepods.slice/pod.slice/cri-containerd-abc.scope",
+			expectedOK: true,
+		},
+		{
+			name:       "sibling prefix",
+			cgroupPath: "/docker/abcd",
+			root:       "/docker/abc",
+			mountPoint: "/sys/fs/cgroup",
+			expected:   "/sys/fs/cgroup",
+			expectedOK: false,
+		},
+		{
+			name:       "mount predating the namespace",
+			cgroupPath: "/",
+			root:       "/..",
+			mountPoint: "/sys/fs/cgroup",
+			expected:   "/sys/fs/cgroup",
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, ok := resolveCGroupDir(tt.cgroupPath, &MountInfo{Root: tt.root, MountPoint: tt.mountPoint})
+			assert.Equal(t, tt.expected, dir)
+			assert.Equal(t, tt.expectedOK, ok)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package cgroups
+
+// PIDs describes the pids controller statistics of the cgroup.
+type PIDs struct {
+	// Current is the number of tasks, i.e. processes and threads, in the
+	// cgroup.
+	Current uint64 `json:"current"`
+	// Limit is the maximum number of tasks, 0 if unlimited.
+	Limit uint64 `json:"limit"`
+}
+
+// Percent returns the number of tasks relative to the limit, 0 if unlimited.
+func (p PIDs) Percent() float64 {
+	if p.Limit == 0 {
+		return 0
+	}
+	return float64(p.Current) * 100 / float64(p.Limit)
+}
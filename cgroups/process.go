@@ -0,0 +1,82 @@
+package cgroups
+
+import (
+	"time"
+)
+
+// ThreadStat is the CPU accounting of a single thread, read from
+// /proc/self/task/<tid>/stat and /proc/self/task/<tid>/schedstat.
+type ThreadStat struct {
+	TID  int    `json:"tid"`
+	Name string `json:"name"`
+	// UserTime and SystemTime are the utime and stime fields of stat.
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+	// RunTime is the time spent on the CPU and WaitTime the time spent
+	// waiting on a run queue, as reported by schedstat.
+	RunTime    time.Duration `json:"run_time"`
+	WaitTime   time.Duration `json:"wait_time"`
+	Timeslices uint64        `json:"timeslices"`
+}
+
+// CPUTime returns the CPU time consumed by the thread, preferring the
+// nanosecond precision of schedstat over the tick-based stat fields.
+func (t ThreadStat) CPUTime() time.Duration {
+	if t.RunTime > 0 {
+		return t.RunTime
+	}
+	return t.UserTime + t.SystemTime
+}
+
+// ProcessStat is the CPU accounting of the current process, read from
+// /proc/self/stat, along with its threads. RunTime, WaitTime and Timeslices
+// are summed over the live threads, so they may decrease when threads exit.
+type ProcessStat struct {
+	Time       time.Time     `json:"time"`
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+	RunTime    time.Duration `json:"run_time"`
+	WaitTime   time.Duration `json:"wait_time"`
+	Timeslices uint64        `json:"timeslices"`
+	Threads    []ThreadStat  `json:"threads,omitempty"`
+}
+
+// CPUTime returns the CPU time consumed by the process, including the
+// threads that already exited.
+func (p ProcessStat) CPUTime() time.Duration {
+	return p.UserTime + p.SystemTime
+}
+
+// ThreadUsage is the CPU usage of a thread between two ProcessStat readings.
+type ThreadUsage struct {
+	TID  int    `json:"tid"`
+	Name string `json:"name"`
+	// Usage is the number of cores used by the thread.
+	Usage float64 `json:"usage"`
+}
+
+// ThreadUsage returns the usage of every thread of p since prev. Threads
+// created after prev are accounted from zero.
+func (p ProcessStat) ThreadUsage(prev ProcessStat) []ThreadUsage {
+	elapsed := p.Time.Sub(prev.Time)
+	if elapsed <= 0 {
+		return nil
+	}
+
+	prevTimes := make(map[int]time.Duration, len(prev.Threads))
+	for _, t := range prev.Threads {
+		prevTimes[t.TID] = t.CPUTime()
+	}
+
+	usage := make([]ThreadUsage, 0, len(p.Threads))
+	for _, t := range p.Threads {
+		delta := max(t.CPUTime()-prevTimes[t.TID], 0)
+		usage = append(usage, ThreadUsage{
+			TID:   t.TID,
+			Name:  t.Name,
+			Usage: float64(delta) / float64(elapsed),
+		})
+	}
+
+	return usage
+}
@@ -0,0 +1,220 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const procSelfPath = "/proc/self"
+
+// ReadProcessStat returns the CPU accounting of the current process and of
+// each of its threads.
+func ReadProcessStat() (ProcessStat, error) {
+	return readProcessStat(procSelfPath)
+}
+
+// readProcessStat reads the stat file of the process at dir, and the stat and
+// schedstat files of each of its tasks. /proc/<pid>/schedstat only accounts
+// for the thread group leader, so the process run and wait times are summed
+// over the tasks instead.
+func readProcessStat(dir string) (ProcessStat, error) {
+	p := ProcessStat{Time: time.Now()}
+
+	line, err := readFirstLine(filepath.Join(dir, "stat"))
+	if err != nil {
+		return ProcessStat{}, err
+	}
+
+	stat, err := parseTaskStat(line)
+	if err != nil {
+		return ProcessStat{}, err
+	}
+
+	p.UserTime = stat.UserTime
+	p.SystemTime = stat.SystemTime
+
+	entries, err := os.ReadDir(filepath.Join(dir, "task"))
+	if err != nil {
+		return ProcessStat{}, err
+	}
+
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		thread, err := readThreadStat(filepath.Join(dir, "task", entry.Name()))
+		if err != nil {
+			// The thread exited since the directory was listed.
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return ProcessStat{}, err
+		}
+
+		thread.TID = tid
+		p.Threads = append(p.Threads, thread)
+		p.RunTime += thread.RunTime
+		p.WaitTime += thread.WaitTime
+		p.Timeslices += thread.Timeslices
+	}
+
+	return p, nil
+}
+
+// readProcessCounters reads the CPU time of the process at dir from its stat
+// file, and the run queue wait of each of its tasks from their schedstat
+// file, keyed by TID. It reads less than readProcessStat, for the Collector.
+// The waits are empty when schedstat is not available.
+func readProcessCounters(dir string) (time.Duration, map[int]time.Duration, error) {
+	line, err := readFirstLine(filepath.Join(dir, "stat"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	stat, err := parseTaskStat(line)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "task"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	waits := make(map[int]time.Duration, len(entries))
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		line, err := readFirstLine(filepath.Join(dir, "task", entry.Name(), "schedstat"))
+		if err != nil {
+			// The thread exited since the directory was listed, or
+			// schedstat requires CONFIG_SCHED_INFO.
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return 0, nil, err
+		}
+
+		var t ThreadStat
+		if err := parseSchedstat(line, &t); err != nil {
+			return 0, nil, err
+		}
+		waits[tid] = t.WaitTime
+	}
+
+	return stat.UserTime + stat.SystemTime, waits, nil
+}
+
+// readThreadStat reads the stat and, when available, schedstat files at dir.
+func readThreadStat(dir string) (ThreadStat, error) {
+	line, err := readFirstLine(filepath.Join(dir, "stat"))
+	if err != nil {
+		return ThreadStat{}, err
+	}
+
+	t, err := parseTaskStat(line)
+	if err != nil {
+		return ThreadStat{}, err
+	}
+
+	line, err = readFirstLine(filepath.Join(dir, "schedstat"))
+	if err != nil {
+		// schedstat requires CONFIG_SCHED_INFO.
+		if errors.Is(err, fs.ErrNotExist) {
+			return t, nil
+		}
+		return ThreadStat{}, err
+	}
+
+	if err := parseSchedstat(line, &t); err != nil {
+		return ThreadStat{}, err
+	}
+
+	return t, nil
+}
+
+// parseTaskStat parses the comm, utime and stime fields of a line in the
+// format of `/proc/<pid>/stat`. comm is enclosed in parentheses and may
+// itself contain spaces and parentheses.
+// https://man7.org/linux/man-pages/man5/proc_pid_stat.5.html
+func parseTaskStat(line string) (ThreadStat, error) {
+	open := strings.IndexByte(line, '(')
+	end := strings.LastIndexByte(line, ')')
+	if open < 0 || end < open {
+		return ThreadStat{}, fmt.Errorf("invalid stat entry: %q", line)
+	}
+
+	// fields starts at field (3) state, so utime (14) and stime (15)
+	// are at index 11 and 12.
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 13 {
+		return ThreadStat{}, fmt.Errorf("invalid stat entry: %q", line)
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return ThreadStat{}, err
+	}
+
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return ThreadStat{}, err
+	}
+
+	return ThreadStat{
+		Name:       line[open+1 : end],
+		UserTime:   ticksToDuration(utime),
+		SystemTime: ticksToDuration(stime),
+	}, nil
+}
+
+// parseSchedstat parses a line in the format of `/proc/<pid>/schedstat`:
+// time spent on the cpu (ns), time spent waiting on a runqueue (ns) and
+// number of timeslices run on this cpu.
+// https://www.kernel.org/doc/Documentation/scheduler/sched-stats.txt
+func parseSchedstat(line string, t *ThreadStat) error {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid schedstat entry: %q", line)
+	}
+
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+
+	t.RunTime = time.Duration(values[0])
+	t.WaitTime = time.Duration(values[1])
+	t.Timeslices = values[2]
+
+	return nil
+}
+
+// ticksToDuration converts clock ticks to a duration. The whole seconds and
+// the remainder are converted separately, as ticks * time.Second overflows
+// once ticks exceed about 1.8e10.
+func ticksToDuration(ticks uint64) time.Duration {
+	return ticksToDurationHz(ticks, clockTicks())
+}
+
+func ticksToDurationHz(ticks, hz uint64) time.Duration {
+	return time.Duration(ticks/hz)*time.Second + time.Duration(ticks%hz)*time.Second/time.Duration(hz)
+}
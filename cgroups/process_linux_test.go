@@ -0,0 +1,132 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTaskStat(t *testing.T) {
+	hz := time.Second / time.Duration(clockTicks())
+
+	testTable := []struct {
+		name         string
+		line         string
+		expectedStat ThreadStat
+		hasErr       bool
+	}{
+		{
+			name:         "simple",
+			line:         "17803 (cat) R 17354 17354 17354 0 -1 4194304 84 0 0 0 7 3 0 0 20 0 1 0 69637 2703360 327",
+			expectedStat: ThreadStat{Name: "cat", UserTime: 7 * hz, SystemTime: 3 * hz},
+		},
+		{
+			name:         "comm-with-spaces-and-parens",
+			line:         "42 (a) b (c) S 1 42 42 0 -1 4194560 10 0 0 0 12 0 0 0 20 0 1 0 100 0 0",
+			expectedStat: ThreadStat{Name: "a) b (c", UserTime: 12 * hz},
+		},
+		{
+			name:   "missing-comm",
+			line:   "42 S 1 42",
+			hasErr: true,
+		},
+		{
+			name:   "fewer-fields",
+			line:   "42 (a) S 1 42 42 0",
+			hasErr: true,
+		},
+	}
+
+	for _, tt := range testTable {
+		stat, err := parseTaskStat(tt.line)
+		assert.Equal(t, tt.expectedStat, stat, tt.name)
+
+		if tt.hasErr {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+	}
+}
+
+func TestReadProcessStat(t *testing.T) {
+	hz := time.Second / time.Duration(clockTicks())
+
+	p, err := readProcessStat(filepath.Join(testDataPath, "proc", "self"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2400*time.Millisecond, p.RunTime)
+	assert.Equal(t, 300*time.Millisecond, p.WaitTime)
+	assert.Equal(t, uint64(1000), p.Timeslices)
+	assert.Equal(t, 300*hz, p.CPUTime())
+
+	require.Len(t, p.Threads, 2)
+	assert.Equal(t, 100, p.Threads[0].TID)
+	assert.Equal(t, "my (app)", p.Threads[0].Name)
+	assert.Equal(t, 2400*time.Millisecond, p.Threads[0].CPUTime())
+	assert.Equal(t, 101, p.Threads[1].TID)
+	assert.Equal(t, "worker 1", p.Threads[1].Name)
+	assert.Zero(t, p.Threads[1].RunTime)
+	assert.Equal(t, p.Threads[1].UserTime+p.Threads[1].SystemTime, p.Threads[1].CPUTime())
+
+	self, err := ReadProcessStat()
+	require.NoError(t, err)
+	assert.NotEmpty(t, self.Threads)
+}
+
+func TestReadProcessCounters(t *testing.T) {
+	hz := time.Second / time.Duration(clockTicks())
+
+	cpu, waits, err := readProcessCounters(filepath.Join(testDataPath, "proc", "self"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 300*hz, cpu)
+	assert.Equal(t, map[int]time.Duration{100: 300 * time.Millisecond}, waits)
+}
+
+func TestThreadUsage(t *testing.T) {
+	start := time.Now()
+	prev := ProcessStat{
+		Time:    start,
+		Threads: []ThreadStat{{TID: 1, RunTime: time.Second}},
+	}
+	cur := ProcessStat{
+		Time: start.Add(2 * time.Second),
+		Threads: []ThreadStat{
+			{TID: 1, Name: "main", RunTime: 2 * time.Second},
+			{TID: 2, Name: "worker", RunTime: time.Second},
+		},
+	}
+
+	assert.Equal(t, []ThreadUsage{
+		{TID: 1, Name: "main", Usage: 0.5},
+		{TID: 2, Name: "worker", Usage: 0.5},
+	}, cur.ThreadUsage(prev))
+	assert.Nil(t, prev.ThreadUsage(cur))
+}
+
+func TestTicksToDuration(t *testing.T) {
+	tests := []struct {
+		ticks    uint64
+		hz       uint64
+		expected time.Duration
+	}{
+		{0, 100, 0},
+		{150, 100, 1500 * time.Millisecond},
+		{1, 1000, time.Millisecond},
+		{1, 300, 3333333 * time.Nanosecond},
+		// ticks * time.Second overflows uint64 beyond ~1.8e10 ticks.
+		{2e10, 100, 2e8 * time.Second},
+		{2e10 + 1, 100, 2e8*time.Second + 10*time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ticksToDurationHz(tt.ticks, tt.hz), "%d ticks at %d Hz", tt.ticks, tt.hz)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package cgroups
+
+import (
+	"errors"
+)
+
+// ReadProcessStat returns the CPU accounting of the current process and of
+// each of its threads.
+func ReadProcessStat() (ProcessStat, error) {
+	return ProcessStat{}, errors.ErrUnsupported
+}
@@ -0,0 +1,225 @@
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProfileDuration = 10 * time.Second
+	defaultProfileCooldown = 5 * time.Minute
+	defaultProfileKeep     = 5
+
+	profileTimeFormat = "20060102T150405.000"
+)
+
+// ProfilerConfig configures a Profiler.
+type ProfilerConfig struct {
+	// Dir is the directory profiles are written to. It is created if missing.
+	Dir string
+	// Duration is the length of each CPU profile. Defaults to 10s.
+	Duration time.Duration
+	// Cooldown is the minimum time between two captures. Defaults to 5m.
+	Cooldown time.Duration
+	// Keep is the number of captures retained in Dir. Defaults to 5.
+	Keep int
+	// Logger receives the failures of the captures started by Observe, at
+	// error level. Defaults to the logger set with SetLogger.
+	Logger *slog.Logger
+}
+
+// A Profiler captures a CPU profile and a goroutine dump when its Detector
+// reports a high-CPU episode. Register it with Collector.OnSample:
+//
+//	p, err := cgroups.NewProfiler(cfg, cgroups.NewDetector(90, time.Minute))
+//	collector.OnSample(p.Observe)
+type Profiler struct {
+	cfg      ProfilerConfig
+	detector *Detector
+
+	mu        sync.Mutex
+	running   bool
+	lastStart time.Time
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewProfiler returns a Profiler triggered by d.
+func NewProfiler(cfg ProfilerConfig, d *Detector) (*Profiler, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("profiler: empty directory")
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = defaultProfileDuration
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultProfileCooldown
+	}
+	if cfg.Keep <= 0 {
+		cfg.Keep = defaultProfileKeep
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Profiler{
+		cfg:      cfg,
+		detector: d,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Observe feeds s to the Detector and starts a capture in the background when
+// a high-CPU episode begins, unless one is running or the cooldown has not
+// elapsed. Capture failures are only logged.
+func (p *Profiler) Observe(s Sample) {
+	if !p.detector.Observe(s) {
+		return
+	}
+
+	if !p.begin(time.Now()) {
+		return
+	}
+
+	go func() {
+		defer p.end()
+		if err := p.capture(); err != nil {
+			p.logger().Error("cgroup: profile capture failed", "dir", p.cfg.Dir, "error", err)
+		}
+	}()
+}
+
+// Capture records a CPU profile for the configured duration followed by a
+// goroutine dump, regardless of the Detector and cooldown. It blocks until
+// the capture is done and returns its error.
+func (p *Profiler) Capture() error {
+	if !p.begin(time.Time{}) {
+		return errors.New("profiler: capture already running or profiler closed")
+	}
+	defer p.end()
+
+	return p.capture()
+}
+
+// Close stops the Profiler and aborts a running capture. The partial CPU
+// profile is still written.
+func (p *Profiler) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
+
+func (p *Profiler) logger() *slog.Logger {
+	if p.cfg.Logger != nil {
+		return p.cfg.Logger
+	}
+	return defaultLogger()
+}
+
+// begin marks a capture as running. A zero now bypasses the cooldown.
+func (p *Profiler) begin(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	if p.running {
+		return false
+	}
+	if !now.IsZero() && !p.lastStart.IsZero() && now.Sub(p.lastStart) < p.cfg.Cooldown {
+		return false
+	}
+
+	p.running = true
+	p.lastStart = time.Now()
+	return true
+}
+
+func (p *Profiler) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.running = false
+}
+
+func (p *Profiler) capture() error {
+	stamp := time.Now().Format(profileTimeFormat)
+
+	cpuFile, err := os.Create(filepath.Join(p.cfg.Dir, fmt.Sprintf("cpu-%s.pprof", stamp)))
+	if err != nil {
+		return err
+	}
+	defer cpuFile.Close()
+
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		// Do not leave an empty profile counted by rotate, e.g. when another
+		// CPU profile is already running.
+		cpuFile.Close()
+		os.Remove(cpuFile.Name())
+		return err
+	}
+
+	timer := time.NewTimer(p.cfg.Duration)
+	select {
+	case <-timer.C:
+	case <-p.done:
+		timer.Stop()
+	}
+	pprof.StopCPUProfile()
+
+	goroutineFile, err := os.Create(filepath.Join(p.cfg.Dir, fmt.Sprintf("goroutine-%s.txt", stamp)))
+	if err != nil {
+		return err
+	}
+	defer goroutineFile.Close()
+
+	if err := pprof.Lookup("goroutine").WriteTo(goroutineFile, 2); err != nil {
+		return err
+	}
+
+	return p.rotate()
+}
+
+// rotate removes the oldest captures beyond the configured number to keep.
+func (p *Profiler) rotate() error {
+	for _, prefix := range []string{"cpu-", "goroutine-"} {
+		matches, err := filepath.Glob(filepath.Join(p.cfg.Dir, prefix+"*"))
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		for _, m := range matches {
+			if strings.HasSuffix(m, ".pprof") || strings.HasSuffix(m, ".txt") {
+				names = append(names, m)
+			}
+		}
+		if len(names) <= p.cfg.Keep {
+			continue
+		}
+
+		// The timestamp format sorts lexically in chronological order.
+		sort.Strings(names)
+		for _, name := range names[:len(names)-p.cfg.Keep] {
+			if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package cgroups
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfilerCapture(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewProfiler(ProfilerConfig{
+		Dir:      dir,
+		Duration: 10 * time.Millisecond,
+		Keep:     2,
+	}, NewDetector(80, 0))
+	require.NoError(t, err)
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.Capture())
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	for _, pattern := range []string{"cpu-*.pprof", "goroutine-*.txt"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		require.NoError(t, err)
+		assert.Len(t, matches, 2, pattern)
+	}
+}
+
+func TestProfilerCooldown(t *testing.T) {
+	p, err := NewProfiler(ProfilerConfig{
+		Dir:      t.TempDir(),
+		Cooldown: time.Hour,
+	}, NewDetector(80, 0))
+	require.NoError(t, err)
+
+	now := time.Now()
+	p.lastStart = now
+	assert.False(t, p.begin(now.Add(time.Minute)))
+	assert.True(t, p.begin(now.Add(2*time.Hour)))
+}
+
+func TestProfilerObserve(t *testing.T) {
+	p, err := NewProfiler(ProfilerConfig{
+		Dir:      t.TempDir(),
+		Duration: time.Hour,
+		Cooldown: time.Hour,
+	}, NewDetector(80, 0))
+	require.NoError(t, err)
+
+	now := time.Now()
+	p.Observe(Sample{Time: now, Percent: 90})
+	assert.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.running
+	}, time.Second, time.Millisecond)
+
+	assert.False(t, p.begin(now), "capture already running")
+	require.NoError(t, p.Close())
+	assert.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return !p.running
+	}, time.Second, time.Millisecond)
+
+	assert.False(t, p.begin(time.Time{}), "profiler closed")
+	_, err = NewProfiler(ProfilerConfig{}, NewDetector(80, 0))
+	assert.Error(t, err)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestProfilerCaptureError(t *testing.T) {
+	var logs syncBuffer
+	dir := filepath.Join(t.TempDir(), "profiles")
+	p, err := NewProfiler(ProfilerConfig{
+		Dir:      dir,
+		Duration: time.Millisecond,
+		Cooldown: time.Nanosecond,
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+	}, NewDetector(80, 0))
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, os.RemoveAll(dir))
+
+	assert.Error(t, p.Capture())
+
+	p.Observe(Sample{Time: time.Now(), Percent: 90})
+	assert.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), `level=ERROR msg="cgroup: profile capture failed"`)
+	}, time.Second, time.Millisecond)
+}
+
+func TestProfilerCaptureProfileRunning(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewProfiler(ProfilerConfig{
+		Dir:      dir,
+		Duration: time.Millisecond,
+	}, NewDetector(80, 0))
+	require.NoError(t, err)
+	defer p.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, pprof.StartCPUProfile(&buf))
+	defer pprof.StopCPUProfile()
+
+	assert.Error(t, p.Capture())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,143 @@
+package cgroups
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultRequestPercentile = 90
+	defaultLimitPercentile   = 99
+	defaultHeadroom          = 0.15
+	defaultThrottleThreshold = 0.05
+	defaultMinMillis         = 10
+)
+
+// RecommenderConfig configures a Recommender. Zero values select the
+// defaults.
+type RecommenderConfig struct {
+	// RequestPercentile is the usage percentile the request is based on.
+	// Defaults to 90.
+	RequestPercentile float64
+	// LimitPercentile is the usage percentile the limit is based on.
+	// Defaults to 99.
+	LimitPercentile float64
+	// Headroom is the fraction added on top of the percentiles.
+	// Defaults to 0.15.
+	Headroom float64
+	// ThrottleThreshold is the ratio of throttled periods above which the
+	// usage is considered capped by the current limit. Defaults to 0.05.
+	ThrottleThreshold float64
+	// MinMillis is the smallest recommended request. Defaults to 10m.
+	MinMillis int64
+}
+
+// Recommendation is a suggested CPU request and limit.
+type Recommendation struct {
+	RequestMillis int64 `json:"request_millis"`
+	LimitMillis   int64 `json:"limit_millis"`
+	// ThrottledRatio is the ratio of throttled periods over the history.
+	ThrottledRatio float64 `json:"throttled_ratio"`
+	// Samples is the number of samples the recommendation is based on.
+	Samples int `json:"samples"`
+}
+
+// Request returns the request in the Kubernetes quantity format.
+func (r Recommendation) Request() string {
+	return FormatMillicores(r.RequestMillis)
+}
+
+// Limit returns the limit in the Kubernetes quantity format.
+func (r Recommendation) Limit() string {
+	return FormatMillicores(r.LimitMillis)
+}
+
+// A Recommender suggests a CPU request and limit from a sample history.
+type Recommender struct {
+	cfg RecommenderConfig
+}
+
+// NewRecommender returns a Recommender configured with cfg.
+func NewRecommender(cfg RecommenderConfig) *Recommender {
+	if cfg.RequestPercentile <= 0 {
+		cfg.RequestPercentile = defaultRequestPercentile
+	}
+	if cfg.LimitPercentile <= 0 {
+		cfg.LimitPercentile = defaultLimitPercentile
+	}
+	if cfg.Headroom <= 0 {
+		cfg.Headroom = defaultHeadroom
+	}
+	if cfg.ThrottleThreshold <= 0 {
+		cfg.ThrottleThreshold = defaultThrottleThreshold
+	}
+	if cfg.MinMillis <= 0 {
+		cfg.MinMillis = defaultMinMillis
+	}
+
+	return &Recommender{cfg: cfg}
+}
+
+// Recommend computes a recommendation from history, oldest first, collected
+// under the cgroup described by info. When the container was throttled more
+// than the threshold, the observed usage is capped by the current limit, so
+// the limit is raised above it instead of being derived from the usage.
+func (r *Recommender) Recommend(info Info, history []Sample) (Recommendation, error) {
+	if len(history) == 0 {
+		return Recommendation{}, errors.New("recommender: no samples")
+	}
+
+	usage := make([]float64, len(history))
+	for i, s := range history {
+		usage[i] = s.Usage
+	}
+	sort.Float64s(usage)
+
+	throttling := history[len(history)-1].Throttling.Sub(history[0].Throttling)
+
+	headroom := 1 + r.cfg.Headroom
+	request := percentile(usage, r.cfg.RequestPercentile) * headroom
+	limit := percentile(usage, r.cfg.LimitPercentile) * headroom
+
+	ratio := throttling.Ratio()
+	if ratio > r.cfg.ThrottleThreshold && info.Limit > 0 {
+		limit = max(limit, info.Limit*headroom)
+	}
+
+	rec := Recommendation{
+		RequestMillis:  max(toMillicores(request), r.cfg.MinMillis),
+		LimitMillis:    toMillicores(limit),
+		ThrottledRatio: ratio,
+		Samples:        len(history),
+	}
+	rec.LimitMillis = max(rec.LimitMillis, rec.RequestMillis)
+
+	return rec, nil
+}
+
+// percentile returns the p-th percentile of sorted using the nearest-rank
+// method.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// toMillicores rounds cores up to the next millicore. cores is first rounded
+// to nanocores, so that float errors, e.g. 0.1*1.1*1000 =
+// 110.00000000000001, do not add a millicore.
+func toMillicores(cores float64) int64 {
+	nanocores := int64(math.Round(cores * 1e9))
+	return (nanocores + 1e6 - 1) / 1e6
+}
+
+// FormatMillicores formats millicores in the Kubernetes quantity format,
+// e.g. `250m`, or `2` for whole cores.
+func FormatMillicores(millis int64) string {
+	if millis%1000 == 0 {
+		return strconv.FormatInt(millis/1000, 10)
+	}
+	return strconv.FormatInt(millis, 10) + "m"
+}
@@ -0,0 +1,99 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usageHistory(usage ...float64) []Sample {
+	history := make([]Sample, len(usage))
+	for i, u := range usage {
+		history[i] = Sample{
+			Usage:      u,
+			Throttling: Throttling{Periods: uint64(i) * 10},
+		}
+	}
+	return history
+}
+
+func TestRecommend(t *testing.T) {
+	r := NewRecommender(RecommenderConfig{Headroom: 0.1})
+
+	history := usageHistory(0.1, 0.2, 0.2, 0.3, 0.3, 0.3, 0.4, 0.4, 0.5, 1.0)
+	rec, err := r.Recommend(Info{Limit: 2}, history)
+	require.NoError(t, err)
+	assert.Equal(t, Recommendation{
+		RequestMillis: 550,
+		LimitMillis:   1100,
+		Samples:       10,
+	}, rec)
+	assert.Equal(t, "550m", rec.Request())
+	assert.Equal(t, "1100m", rec.Limit())
+
+	_, err = r.Recommend(Info{}, nil)
+	assert.Error(t, err)
+}
+
+func TestRecommendThrottled(t *testing.T) {
+	r := NewRecommender(RecommenderConfig{})
+
+	history := usageHistory(0.5, 0.5, 0.5, 0.5)
+	history[3].Throttling.ThrottledPeriods = 15
+
+	rec, err := r.Recommend(Info{Limit: 0.5}, history)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, rec.ThrottledRatio, 1e-9)
+	assert.Equal(t, int64(575), rec.RequestMillis)
+	assert.Equal(t, int64(575), rec.LimitMillis)
+
+	rec, err = r.Recommend(Info{Limit: 2}, history)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2300), rec.LimitMillis)
+
+	// The high percentile usage is below the limit, which is still raised
+	// above it.
+	history = usageHistory(0.2, 0.3, 0.3, 0.4)
+	history[3].Throttling.ThrottledPeriods = 10
+
+	rec, err = r.Recommend(Info{Limit: 1}, history)
+	require.NoError(t, err)
+	assert.Equal(t, int64(460), rec.RequestMillis)
+	assert.Equal(t, int64(1150), rec.LimitMillis)
+}
+
+func TestToMillicores(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected int64
+	}{
+		{0, 0},
+		{0.1, 100},
+		{0.1 * 1.1, 110},
+		{0.3 * 1.15, 345},
+		{0.0001, 1},
+		{0.1001, 101},
+		{2, 2000},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, toMillicores(tt.input), tt.input)
+	}
+}
+
+func TestFormatMillicores(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{0, "0"},
+		{10, "10m"},
+		{1500, "1500m"},
+		{2000, "2"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, FormatMillicores(tt.input))
+	}
+}
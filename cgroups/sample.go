@@ -0,0 +1,173 @@
+package cgroups
+
+import (
+	"math"
+	"time"
+)
+
+// Sample is a single observation of the container CPU usage.
+type Sample struct {
+	Time time.Time `json:"time"`
+	// Usage is the number of cores used during the sampling interval.
+	Usage float64 `json:"usage"`
+	// Percent is the usage relative to the container CPU limit.
+	Percent float64 `json:"percent"`
+	// CPUTime is the cumulative CPU time consumed by the cgroup.
+	CPUTime time.Duration `json:"cpu_time"`
+	// ProcessUsage is the number of cores used by the current process.
+	ProcessUsage float64 `json:"process_usage"`
+	// ProcessShare is the fraction of the container usage attributable to
+	// the current process, between 0 and 1.
+	ProcessShare float64 `json:"process_share"`
+	// Runtime is the Go runtime CPU breakdown as a percent of the container
+	// CPU usage, estimated over the window between the last two garbage
+	// collections rather than the sampling interval.
+	Runtime RuntimeUsage `json:"runtime"`
+	// RunQueueWait is the time the threads of the current process spent
+	// waiting on a run queue during the sampling interval.
+	RunQueueWait time.Duration `json:"run_queue_wait"`
+	// Throttled is the time the cgroup was throttled during the sampling
+	// interval.
+	Throttled time.Duration `json:"throttled"`
+	// Throttling holds the cumulative CFS throttling counters.
+	Throttling Throttling `json:"throttling"`
+	// Memory holds the memory controller statistics at the time of the
+	// sample.
+	Memory Memory `json:"memory"`
+	// IO holds the cumulative IO controller statistics at the time of the
+	// sample.
+	IO IO `json:"io"`
+	// PIDs holds the pids controller statistics at the time of the sample.
+	PIDs PIDs `json:"pids"`
+}
+
+// Throttling describes the CFS bandwidth statistics of the cgroup,
+// read from the nr_periods, nr_throttled, nr_bursts and throttled_time and
+// burst_time (v1) or throttled_usec and burst_usec (v2) fields of cpu.stat.
+type Throttling struct {
+	Periods          uint64        `json:"periods"`
+	ThrottledPeriods uint64        `json:"throttled_periods"`
+	ThrottledTime    time.Duration `json:"throttled_time"`
+	// Bursts is the number of periods in which the burst budget was used,
+	// and BurstTime the CPU time consumed above the quota.
+	Bursts    uint64        `json:"bursts"`
+	BurstTime time.Duration `json:"burst_time"`
+}
+
+// Sub returns the counters accumulated since prev.
+func (t Throttling) Sub(prev Throttling) Throttling {
+	return Throttling{
+		Periods:          subUint(t.Periods, prev.Periods),
+		ThrottledPeriods: subUint(t.ThrottledPeriods, prev.ThrottledPeriods),
+		ThrottledTime:    max(t.ThrottledTime-prev.ThrottledTime, 0),
+		Bursts:           subUint(t.Bursts, prev.Bursts),
+		BurstTime:        max(t.BurstTime-prev.BurstTime, 0),
+	}
+}
+
+// Ratio returns the fraction of enforcement periods that were throttled.
+func (t Throttling) Ratio() float64 {
+	if t.Periods == 0 {
+		return 0
+	}
+	return float64(t.ThrottledPeriods) / float64(t.Periods)
+}
+
+// Info describes the cgroup the current process belongs to.
+type Info struct {
+	// Version is the cgroup hierarchy version, 1 or 2.
+	Version int `json:"version"`
+	// Path is the directory the cgroup files are read from.
+	Path string `json:"path"`
+	// CGroup is the path of the cgroup in /proc/self/cgroup, relative to
+	// the root of the cgroup namespace. Unlike Path, it keeps the container
+	// and pod IDs when the hierarchy is mounted from inside the container.
+	CGroup string `json:"cgroup,omitempty"`
+	// Namespace is the inode of the cgroup namespace, 0 if unsupported.
+	// PrivateNamespace reports whether it is not the initial namespace, in
+	// which case /proc/self/cgroup only shows the path below the namespace
+	// root.
+	Namespace        uint64 `json:"namespace,omitempty"`
+	PrivateNamespace bool   `json:"private_namespace"`
+	// Controllers are the controllers available to the cgroup: the mounted
+	// hierarchies on v1, the content of cgroup.controllers on v2. It is nil
+	// if unknown.
+	Controllers []string `json:"controllers,omitempty"`
+	// Quota is the CPU quota in cores, -1 if unlimited.
+	Quota float64 `json:"quota"`
+	// QuotaPath is the cgroup defining Quota, either the cgroup itself or
+	// the ancestor with the tightest quota.
+	QuotaPath string `json:"quota_path,omitempty"`
+	// Burst is the burst budget allowed on top of Quota, in cores.
+	Burst float64 `json:"burst"`
+	// CPUSet is the raw cpuset list, e.g. `0-3,6`.
+	CPUSet        string `json:"cpuset"`
+	EffectiveCPUs int    `json:"effective_cpus"`
+	// Limit is the number of cores available to the container, i.e. the
+	// smaller of Quota and EffectiveCPUs. With WithBurstLimit, Quota plus
+	// Burst is used instead of Quota.
+	Limit float64 `json:"limit"`
+	// Weight is the relative share of CPU under contention.
+	Weight CPUWeight `json:"weight"`
+	// Sched holds the utilization clamps and idle state, nil on v1.
+	Sched *SchedAttrs `json:"sched,omitempty"`
+}
+
+// computeCPUUsage returns the cores used and the percent of limit, given the
+// cgroup and host CPU time elapsed between two readings.
+func computeCPUUsage(cpuDelta, systemDelta, cpuCores, cores, limit float64) (float64, float64) {
+	var (
+		cpuUsage   = 0.0
+		cpuPercent = 0.0
+	)
+
+	if systemDelta > 0 && cpuDelta > 0 {
+		cpuUsage = (cpuDelta / systemDelta) * cpuCores
+		if limit > 0 {
+			cpuPercent = cpuDelta * cores * 100 / (systemDelta * limit)
+		}
+	}
+
+	return cpuUsage, cpuPercent
+}
+
+// RunQueueCorrelation returns the Pearson correlation coefficient between
+// the run queue wait and the throttled time of samples. A value close to 1
+// means the scheduling delay is caused by the cgroup quota rather than by
+// contention with other workloads on the host. It returns 0 when either
+// series is constant.
+func RunQueueCorrelation(samples []Sample) float64 {
+	n := float64(len(samples))
+	if n < 2 {
+		return 0
+	}
+
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += float64(s.RunQueueWait)
+		sumY += float64(s.Throttled)
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for _, s := range samples {
+		dx := float64(s.RunQueueWait) - meanX
+		dy := float64(s.Throttled) - meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+
+	return cov / math.Sqrt(varX*varY)
+}
+
+func subUint(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
@@ -0,0 +1,13 @@
+package cgroups
+
+// SchedAttrs describes how the scheduler treats the cgroup. It is only
+// available on cgroup v2.
+type SchedAttrs struct {
+	// UclampMin and UclampMax are the utilization clamps of cpu.uclamp.min
+	// and cpu.uclamp.max, in percent.
+	UclampMin float64 `json:"uclamp_min"`
+	UclampMax float64 `json:"uclamp_max"`
+	// Idle reports whether cpu.idle marks the cgroup as SCHED_IDLE, i.e.
+	// running only when nothing else wants the CPU.
+	Idle bool `json:"idle"`
+}
@@ -1,23 +1,107 @@
-//go:build linux
-// +build linux
-
 package cgroups
 
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 )
 
+const procCGroupPath = "/proc/self/cgroup"
+
 // the data structure for entities in `/proc/$PID/cgroup`.
 // See also cgroups(7) for more information.
 // https://man7.org/linux/man-pages/man7/cgroups.7.html
 type Subsystem struct {
-	ID         int
-	Subsystems []string
-	Name       string
+	ID         int      `json:"id"`
+	Subsystems []string `json:"subsystems"`
+	Name       string   `json:"name"`
+}
+
+// IsUnified reports whether s is the cgroup v2 entry, `0::<path>`.
+func (s *Subsystem) IsUnified() bool {
+	return s.ID == 0 && len(s.Subsystems) == 1 && s.Subsystems[0] == ""
+}
+
+// NamedHierarchy returns the name of the v1 named hierarchy of s without the
+// `name=` prefix, e.g. `systemd` for `1:name=systemd:/`.
+func (s *Subsystem) NamedHierarchy() (string, bool) {
+	for _, subsys := range s.Subsystems {
+		if name, ok := strings.CutPrefix(subsys, "name="); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Subsystems are the entries of `/proc/$PID/cgroup`, in the order of the file.
+type Subsystems []*Subsystem
+
+// Unified returns the cgroup v2 entry, or nil if there is none.
+func (ss Subsystems) Unified() *Subsystem {
+	for _, s := range ss {
+		if s.IsUnified() {
+			return s
+		}
+	}
+	return nil
+}
+
+// Controller returns the v1 entry of the controller, e.g. `cpu`, or nil if
+// there is none.
+func (ss Subsystems) Controller(controller string) *Subsystem {
+	for _, s := range ss {
+		for _, subsys := range s.Subsystems {
+			if subsys == controller {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// Named returns the entry of the v1 named hierarchy, e.g. `systemd` for
+// `name=systemd`, or nil if there is none.
+func (ss Subsystems) Named(name string) *Subsystem {
+	return ss.Controller("name=" + name)
+}
+
+// ReadCGroups returns the entries of `/proc/<pid>/cgroup`, in order. Use
+// os.Getpid() for the current process.
+func ReadCGroups(pid int) (Subsystems, error) {
+	return readCGroups(fmt.Sprintf("/proc/%d/cgroup", pid))
+}
+
+func readCGroups(path string) (Subsystems, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseCGroups(f)
+}
+
+// ParseCGroups parses content in the format of `/proc/<pid>/cgroup`.
+func ParseCGroups(r io.Reader) (Subsystems, error) {
+	var subsystems Subsystems
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		cgroup, err := parseCGroupSubsysFromLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		subsystems = append(subsystems, cgroup)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return subsystems, nil
 }
 
 // parseCGroupSubsysFromLine returns a new *Subsystem by parsing a string in
@@ -44,30 +128,20 @@ func parseCGroupSubsysFromLine(line string) (*Subsystem, error) {
 	return cgroup, nil
 }
 
-// parseCGroupSubsystems parses procPathCGroup (`/proc/pid/cgroup`)
+// parseCGroupSubsystems parses procPathCGroup (`/proc/pid/cgroup`) into a
+// map keyed by controller. The v2 entry is keyed by the empty string.
 func parseCGroupSubsystems(path string) (map[string]*Subsystem, error) {
-	cgroupFile, err := os.Open(path)
+	cgroups, err := readCGroups(path)
 	if err != nil {
 		return nil, err
 	}
-	defer cgroupFile.Close()
 
-	scanner := bufio.NewScanner(cgroupFile)
 	subsystems := make(map[string]*Subsystem)
-
-	for scanner.Scan() {
-		cgroup, err := parseCGroupSubsysFromLine(scanner.Text())
-		if err != nil {
-			return nil, err
-		}
+	for _, cgroup := range cgroups {
 		for _, subsys := range cgroup.Subsystems {
 			subsystems[subsys] = cgroup
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
 	return subsystems, nil
 }
@@ -5,10 +5,13 @@ package cgroups
 
 import (
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseCGroupSubsysFromLine(t *testing.T) {
@@ -83,3 +86,48 @@ func TestParseCGroupSubsysFromLineErr(t *testing.T) {
 		assert.Equal(t, tt.expectedError, err, tt.name)
 	}
 }
+
+func TestParseCGroups(t *testing.T) {
+	content := `12:cpu,cpuacct:/docker/abc
+11:name=systemd:/docker/abc
+1:name=openrc:/
+0::/docker/abc
+`
+	subsystems, err := ParseCGroups(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, subsystems, 4)
+
+	var ids []int
+	for _, s := range subsystems {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []int{12, 11, 1, 0}, ids)
+
+	unified := subsystems.Unified()
+	require.NotNil(t, unified)
+	assert.True(t, unified.IsUnified())
+	assert.Equal(t, "/docker/abc", unified.Name)
+
+	assert.Equal(t, subsystems[0], subsystems.Controller("cpuacct"))
+	assert.Nil(t, subsystems.Controller("memory"))
+
+	systemd := subsystems.Named("systemd")
+	require.NotNil(t, systemd)
+	assert.Equal(t, 11, systemd.ID)
+	name, ok := systemd.NamedHierarchy()
+	assert.True(t, ok)
+	assert.Equal(t, "systemd", name)
+
+	_, ok = subsystems[0].NamedHierarchy()
+	assert.False(t, ok)
+	assert.False(t, subsystems[0].IsUnified())
+
+	_, err = ParseCGroups(strings.NewReader("1:cpu\n"))
+	assert.Error(t, err)
+}
+
+func TestReadCGroups(t *testing.T) {
+	subsystems, err := ReadCGroups(os.Getpid())
+	require.NoError(t, err)
+	assert.NotEmpty(t, subsystems)
+}
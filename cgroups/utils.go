@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -37,6 +39,66 @@ func readInt(filename string) (int, error) {
 	return strconv.Atoi(text)
 }
 
+// walkQuota returns the smallest positive quota read from dir and its
+// ancestors up to root, and the directory defining it. The quota is -1 if
+// none of them is limited. Only dir is read when it is not below root.
+func walkQuota(dir string, root string, read func(dir string) (float64, error)) (float64, string, error) {
+	quota, binding := -1.0, ""
+
+	dir, root = filepath.Clean(dir), filepath.Clean(root)
+	for {
+		q, err := read(dir)
+		if err != nil {
+			return -1, "", err
+		}
+		if q > 0 && (quota < 0 || q < quota) {
+			quota, binding = q, dir
+		}
+
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir || !isWithin(parent, root) {
+			break
+		}
+		dir = parent
+	}
+
+	return quota, binding, nil
+}
+
+// isWithin reports whether dir is root or one of its descendants.
+func isWithin(dir string, root string) bool {
+	return dir == root || strings.HasPrefix(dir, strings.TrimSuffix(root, "/")+"/")
+}
+
+// readPIDs reads pids.current and pids.max from the pids controller at dir.
+// The files are missing when the controller is not enabled.
+func readPIDs(dir string) (PIDs, error) {
+	current, err := readFirstLine(filepath.Join(dir, "pids.current"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return PIDs{}, nil
+		}
+		return PIDs{}, err
+	}
+
+	var p PIDs
+	if p.Current, err = parseUint(current); err != nil {
+		return PIDs{}, err
+	}
+
+	limit, err := readFirstLine(filepath.Join(dir, "pids.max"))
+	if err != nil {
+		return PIDs{}, err
+	}
+	if limit != "max" {
+		if p.Limit, err = parseUint(limit); err != nil {
+			return PIDs{}, err
+		}
+	}
+
+	return p, nil
+}
+
 func parseUint(s string) (uint64, error) {
 	v, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
@@ -93,3 +155,14 @@ func parseUints(val string) ([]uint64, error) {
 
 	return sets, nil
 }
+
+// parseNamespaceLink returns the inode of a namespace link in the format of
+// `/proc/<pid>/ns/<type>`, e.g. `cgroup:[4026531835]`.
+func parseNamespaceLink(link string) (uint64, error) {
+	_, inode, ok := strings.Cut(link, ":[")
+	if !ok || !strings.HasSuffix(inode, "]") {
+		return 0, fmt.Errorf("invalid namespace link: %q", link)
+	}
+
+	return strconv.ParseUint(strings.TrimSuffix(inode, "]"), 10, 64)
+}
@@ -152,3 +152,14 @@ func TestParseUints(t *testing.T) {
 		assert.Equal(t, tt.want, got)
 	}
 }
+
+func TestParseNamespaceLink(t *testing.T) {
+	inode, err := parseNamespaceLink("cgroup:[4026531835]")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4026531835), inode)
+
+	for _, link := range []string{"cgroup", "cgroup:[abc]", "cgroup:[123"} {
+		_, err := parseNamespaceLink(link)
+		assert.Error(t, err, link)
+	}
+}
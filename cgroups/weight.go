@@ -0,0 +1,78 @@
+package cgroups
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CPUWeight describes the share of CPU the cgroup is entitled to under
+// contention, relative to its siblings.
+type CPUWeight struct {
+	// Weight is the v2 cpu.weight, between 1 and 10000 (default 100).
+	Weight uint64 `json:"weight,omitempty"`
+	// Nice is the v2 cpu.weight.nice, the weight expressed as a nice value.
+	Nice int `json:"nice,omitempty"`
+	// Shares is the v1 cpu.shares (default 1024).
+	Shares uint64 `json:"shares,omitempty"`
+	// SiblingsTotal is the sum of the weights, or shares, of the cgroup and
+	// its siblings, 0 if the parent cgroup is not readable.
+	SiblingsTotal uint64 `json:"siblings_total,omitempty"`
+	// GuaranteedCores estimates the cores available to the cgroup when all
+	// its siblings are busy, 0 if unknown.
+	GuaranteedCores float64 `json:"guaranteed_cores,omitempty"`
+}
+
+// guaranteedCores returns the share of capacity cores w is entitled to.
+func (w CPUWeight) guaranteedCores(capacity float64) float64 {
+	weight := w.Weight
+	if weight == 0 {
+		weight = w.Shares
+	}
+	if weight == 0 || w.SiblingsTotal == 0 {
+		return 0
+	}
+	return capacity * float64(weight) / float64(w.SiblingsTotal)
+}
+
+// siblingWeights returns the sum of the file values of dir and its
+// siblings. It returns 0 if the parent of dir is not a cgroup of the same
+// hierarchy, i.e. it has no such file itself.
+func siblingWeights(dir string, file string) (uint64, error) {
+	parent := filepath.Dir(dir)
+	if _, err := os.Stat(filepath.Join(parent, file)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		text, err := readFirstLine(filepath.Join(parent, entry.Name(), file))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return 0, err
+		}
+
+		v, err := parseUint(text)
+		if err != nil {
+			return 0, err
+		}
+		total += v
+	}
+
+	return total, nil
+}
@@ -0,0 +1,225 @@
+// Command ccu inspects the cgroup CPU configuration and usage of the
+// container it runs in.
+//
+// Usage:
+//
+//	ccu [-v] info [-json]
+//	ccu [-v] doctor [-json]
+//
+// The -v flag logs the cgroup detection decisions to stderr.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+func main() {
+	flag.Usage = usage
+	verbose := flag.Bool("v", false, "log the cgroup detection decisions to stderr")
+	flag.Parse()
+
+	if *verbose {
+		cgroups.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "info":
+		err = runInfo(os.Stdout, args)
+	case "doctor":
+		err = runDoctor(os.Stdout, args)
+	default:
+		fmt.Fprintf(os.Stderr, "ccu: unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ccu: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: ccu [-v] <command> [flags]
+
+Commands:
+  info    print the cgroup CPU configuration
+  doctor  explain how the cgroup is detected and report inconsistencies
+`)
+}
+
+func runInfo(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "print the information as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := cgroups.NewCollector()
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c.Info())
+	}
+
+	return printInfo(w, c.Info())
+}
+
+// printInfo writes info as aligned key-value lines.
+func printInfo(w io.Writer, info cgroups.Info) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "version:\tv%d\n", info.Version)
+	fmt.Fprintf(tw, "path:\t%s\n", info.Path)
+	if info.PrivateNamespace {
+		fmt.Fprintf(tw, "cgroupns:\tprivate (%d)\n", info.Namespace)
+	}
+	if info.Controllers != nil {
+		fmt.Fprintf(tw, "controllers:\t%s\n", strings.Join(info.Controllers, " "))
+	}
+	switch {
+	case info.Quota > 0 && info.QuotaPath != "" && info.QuotaPath != info.Path:
+		fmt.Fprintf(tw, "quota:\t%.2f cores (set by %s)\n", info.Quota, info.QuotaPath)
+	case info.Quota > 0:
+		fmt.Fprintf(tw, "quota:\t%.2f cores\n", info.Quota)
+	default:
+		fmt.Fprintf(tw, "quota:\tunlimited\n")
+	}
+	if info.Burst > 0 {
+		fmt.Fprintf(tw, "burst:\t%.2f cores\n", info.Burst)
+	}
+	fmt.Fprintf(tw, "cpuset:\t%s (%d cpus)\n", info.CPUSet, info.EffectiveCPUs)
+	fmt.Fprintf(tw, "limit:\t%.2f cores\n", info.Limit)
+
+	switch {
+	case info.Weight.Weight > 0:
+		fmt.Fprintf(tw, "weight:\t%d (nice %d)\n", info.Weight.Weight, info.Weight.Nice)
+	case info.Weight.Shares > 0:
+		fmt.Fprintf(tw, "shares:\t%d\n", info.Weight.Shares)
+	}
+	if info.Weight.GuaranteedCores > 0 {
+		fmt.Fprintf(tw, "guaranteed:\t%.2f cores\n", info.Weight.GuaranteedCores)
+	}
+
+	if info.Sched != nil {
+		fmt.Fprintf(tw, "uclamp.min:\t%.2f%%\n", info.Sched.UclampMin)
+		fmt.Fprintf(tw, "uclamp.max:\t%.2f%%\n", info.Sched.UclampMax)
+		fmt.Fprintf(tw, "idle:\t%t\n", info.Sched.Idle)
+	}
+
+	return tw.Flush()
+}
+
+func runDoctor(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "print the diagnosis as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The partial report is still printed when the core inputs cannot be
+	// read, before failing with their error.
+	d, err := cgroups.Diagnose()
+	if d == nil {
+		return err
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(d); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+
+	if printErr := printDiagnosis(w, d); printErr != nil {
+		return printErr
+	}
+	return err
+}
+
+// printDiagnosis writes d as indented sections.
+func printDiagnosis(w io.Writer, d *cgroups.Diagnosis) error {
+	fmt.Fprintf(w, "filesystem: %s (%#x)\n", d.FSType, d.Magic)
+
+	fmt.Fprintln(w, "\n/proc/self/cgroup:")
+	for _, subsys := range d.CGroups {
+		fmt.Fprintf(w, "  %d:%s:%s\n", subsys.ID, strings.Join(subsys.Subsystems, ","), subsys.Name)
+	}
+
+	fmt.Fprintln(w, "\nmounts:")
+	for _, line := range d.Mounts {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+
+	if len(d.Controllers) > 0 {
+		fmt.Fprintln(w, "\ncontrollers:")
+		names := make([]string, 0, len(d.Controllers))
+		for name := range d.Controllers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			fmt.Fprintf(tw, "  %s\t%s\n", name, d.Controllers[name])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "\nfiles:")
+	for _, f := range d.Files {
+		if f.Error != "" {
+			fmt.Fprintf(w, "  %s: %s\n", f.Path, f.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  %s:\n", f.Path)
+		for _, line := range strings.Split(f.Content, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+
+	if d.Info != nil {
+		fmt.Fprintln(w, "\ninfo:")
+		var buf strings.Builder
+		if err := printInfo(&buf, *d.Info); err != nil {
+			return err
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	fmt.Fprintln(w, "\nproblems:")
+	if len(d.Problems) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, p := range d.Problems {
+		fmt.Fprintf(w, "  - %s\n", p)
+	}
+
+	return nil
+}
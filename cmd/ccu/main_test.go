@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+func TestPrintInfo(t *testing.T) {
+	var buf bytes.Buffer
+	err := printInfo(&buf, cgroups.Info{
+		Version:       2,
+		Path:          "/sys/fs/cgroup",
+		Quota:         1.5,
+		CPUSet:        "0-3",
+		EffectiveCPUs: 4,
+		Limit:         1.5,
+		Weight:        cgroups.CPUWeight{Weight: 100},
+		Sched:         &cgroups.SchedAttrs{UclampMax: 100, Idle: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `version:     v2
+path:        /sys/fs/cgroup
+quota:       1.50 cores
+cpuset:      0-3 (4 cpus)
+limit:       1.50 cores
+weight:      100 (nice 0)
+uclamp.min:  0.00%
+uclamp.max:  100.00%
+idle:        true
+`, buf.String())
+}
+
+func TestPrintDiagnosis(t *testing.T) {
+	var buf bytes.Buffer
+	err := printDiagnosis(&buf, &cgroups.Diagnosis{
+		Magic:   0x63677270,
+		FSType:  "cgroup2",
+		CGroups: cgroups.Subsystems{{ID: 0, Subsystems: []string{""}, Name: "/app"}},
+		Mounts:  []string{"30 23 0:26 / /sys/fs/cgroup rw - cgroup2 cgroup2 rw"},
+		Files: []cgroups.DiagnosisFile{
+			{Path: "/sys/fs/cgroup/app/cpu.max", Content: "max 100000"},
+			{Path: "/sys/fs/cgroup/app/cpu.idle", Error: "no such file or directory"},
+		},
+		Problems: []string{"cpuset is empty"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `filesystem: cgroup2 (0x63677270)
+
+/proc/self/cgroup:
+  0::/app
+
+mounts:
+  30 23 0:26 / /sys/fs/cgroup rw - cgroup2 cgroup2 rw
+
+files:
+  /sys/fs/cgroup/app/cpu.max:
+    max 100000
+  /sys/fs/cgroup/app/cpu.idle: no such file or directory
+
+problems:
+  - cpuset is empty
+`, buf.String())
+}
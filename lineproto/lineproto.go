@@ -0,0 +1,255 @@
+// Package lineproto encodes container CPU samples as InfluxDB line protocol
+// or Graphite plaintext lines, and writes them to a network connection or
+// any io.Writer.
+//
+// A Writer is typically fed from a cgroups.Collector. The observers of the
+// Collector must not block, so the samples are handed to a goroutine doing
+// the network writes, and dropped when it falls behind:
+//
+//	w, err := lineproto.Dial("tcp", "localhost:2003", &lineproto.GraphiteEncoder{})
+//	samples := make(chan cgroups.Sample, 16)
+//	collector.OnSample(func(s cgroups.Sample) {
+//		select {
+//		case samples <- s:
+//		default:
+//		}
+//	})
+//	go func() {
+//		for s := range samples {
+//			_ = w.Write(collector.Info(), s)
+//		}
+//	}()
+package lineproto
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+const (
+	defaultMeasurement = "container_cpu"
+	// writeTimeout bounds the writes to a connection opened with Dial, so a
+	// stalled peer cannot block the writer forever.
+	writeTimeout = 5 * time.Second
+)
+
+// An Encoder appends the lines describing a sample to a buffer.
+type Encoder interface {
+	Encode(buf *bytes.Buffer, info cgroups.Info, s cgroups.Sample)
+}
+
+// field is a named value of a sample.
+type field struct {
+	name  string
+	value float64
+	// integer fields are encoded with the InfluxDB `i` suffix.
+	integer bool
+}
+
+// fields returns the values encoded for a sample, in a stable order.
+func fields(info cgroups.Info, s cgroups.Sample) []field {
+	return []field{
+		{name: "usage", value: s.Usage},
+		{name: "percent", value: s.Percent},
+		{name: "limit", value: info.Limit},
+		{name: "cpu_time", value: s.CPUTime.Seconds()},
+		{name: "process_usage", value: s.ProcessUsage},
+		{name: "throttled_time", value: s.Throttled.Seconds()},
+		{name: "periods", value: float64(s.Throttling.Periods), integer: true},
+		{name: "throttled_periods", value: float64(s.Throttling.ThrottledPeriods), integer: true},
+	}
+}
+
+// InfluxEncoder encodes a sample as a single InfluxDB line protocol line:
+//
+//	container_cpu,cgroup_version=2 usage=0.5,percent=25,...,periods=10i 1700000000000000000
+//
+// https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+type InfluxEncoder struct {
+	// Measurement defaults to `container_cpu`.
+	Measurement string
+	// Tags are added to the cgroup_version tag of every line.
+	Tags map[string]string
+}
+
+// Encode implements Encoder.
+func (e *InfluxEncoder) Encode(buf *bytes.Buffer, info cgroups.Info, s cgroups.Sample) {
+	measurement := e.Measurement
+	if measurement == "" {
+		measurement = defaultMeasurement
+	}
+	buf.WriteString(measurementEscaper.Replace(measurement))
+
+	tags := map[string]string{"cgroup_version": strconv.Itoa(info.Version)}
+	for k, v := range e.Tags {
+		tags[k] = v
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	// Sorted tags are recommended for write performance.
+	sort.Strings(keys)
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		buf.WriteByte(',')
+		buf.WriteString(keyEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(keyEscaper.Replace(tags[k]))
+	}
+
+	for i, f := range fields(info, s) {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(f.name)
+		buf.WriteByte('=')
+		if f.integer {
+			buf.WriteString(strconv.FormatUint(uint64(f.value), 10))
+			buf.WriteByte('i')
+		} else {
+			buf.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+		}
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(s.Time.UnixNano(), 10))
+	buf.WriteByte('\n')
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// GraphiteEncoder encodes a sample as Graphite plaintext lines, one per
+// value:
+//
+//	<prefix>cpu.usage 0.5 1700000000
+//
+// https://graphite.readthedocs.io/en/latest/feeding-carbon.html
+type GraphiteEncoder struct {
+	// Prefix is prepended to every metric path, e.g. `servers.app1.`.
+	Prefix string
+}
+
+// Encode implements Encoder.
+func (e *GraphiteEncoder) Encode(buf *bytes.Buffer, info cgroups.Info, s cgroups.Sample) {
+	timestamp := strconv.FormatInt(s.Time.Unix(), 10)
+
+	for _, f := range fields(info, s) {
+		buf.WriteString(e.Prefix)
+		buf.WriteString("cpu.")
+		buf.WriteString(f.name)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(timestamp)
+		buf.WriteByte('\n')
+	}
+}
+
+// A Writer encodes samples and writes them to an io.Writer. Each sample is
+// written with a single Write call, i.e. a single datagram over UDP. It is
+// safe for concurrent use.
+type Writer struct {
+	enc Encoder
+
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewWriter returns a Writer encoding samples with enc to w.
+func NewWriter(w io.Writer, enc Encoder) *Writer {
+	return &Writer{
+		enc: enc,
+		w:   w,
+	}
+}
+
+// Dial connects to addr on the named network, `tcp` or `udp`, and returns a
+// Writer encoding samples with enc to the connection. When a write fails,
+// the connection is closed and a new one is dialed by the next Write, so the
+// Writer recovers from a restarted server.
+func Dial(network, addr string, enc Encoder) (*Writer, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriter(&redialConn{network: network, addr: addr, conn: conn}, enc), nil
+}
+
+// redialConn is a connection dialed again after a failed write. It is not
+// safe for concurrent use, the Writer serializes the calls.
+type redialConn struct {
+	network string
+	addr    string
+	conn    net.Conn
+}
+
+func (c *redialConn) Write(p []byte) (int, error) {
+	if c.conn == nil {
+		conn, err := net.DialTimeout(c.network, c.addr, writeTimeout)
+		if err != nil {
+			return 0, err
+		}
+		c.conn = conn
+	}
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return 0, err
+	}
+
+	n, err := c.conn.Write(p)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return n, err
+}
+
+func (c *redialConn) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Write encodes s and writes it to the underlying writer.
+func (w *Writer) Write(info cgroups.Info, s cgroups.Sample) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Reset()
+	w.enc.Encode(&w.buf, info, s)
+
+	_, err := w.w.Write(w.buf.Bytes())
+	return err
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package lineproto
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+var (
+	testInfo   = cgroups.Info{Version: 2, Limit: 2}
+	testSample = cgroups.Sample{
+		Time:       time.Unix(1700000000, 5),
+		Usage:      0.5,
+		Percent:    25,
+		CPUTime:    90 * time.Second,
+		Throttled:  250 * time.Millisecond,
+		Throttling: cgroups.Throttling{Periods: 10, ThrottledPeriods: 2},
+	}
+)
+
+func TestInfluxEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := &InfluxEncoder{
+		Measurement: "cpu usage",
+		Tags:        map[string]string{"pod": "web,1", "env": "a=b", "empty": ""},
+	}
+	enc.Encode(&buf, testInfo, testSample)
+
+	assert.Equal(t, `cpu\ usage,cgroup_version=2,env=a\=b,pod=web\,1 `+
+		`usage=0.5,percent=25,limit=2,cpu_time=90,process_usage=0,throttled_time=0.25,periods=10i,throttled_periods=2i `+
+		"1700000000000000005\n", buf.String())
+}
+
+func TestGraphiteEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	(&GraphiteEncoder{Prefix: "app."}).Encode(&buf, testInfo, testSample)
+
+	assert.Equal(t, `app.cpu.usage 0.5 1700000000
+app.cpu.percent 25 1700000000
+app.cpu.limit 2 1700000000
+app.cpu.cpu_time 90 1700000000
+app.cpu.process_usage 0 1700000000
+app.cpu.throttled_time 0.25 1700000000
+app.cpu.periods 10 1700000000
+app.cpu.throttled_periods 2 1700000000
+`, buf.String())
+}
+
+func TestWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	w, err := Dial("tcp", ln.Addr().String(), &InfluxEncoder{})
+	require.NoError(t, err)
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, w.Write(testInfo, testSample))
+	require.NoError(t, w.Write(testInfo, testSample))
+	require.NoError(t, w.Close())
+
+	scanner := bufio.NewScanner(conn)
+	lines := 0
+	for scanner.Scan() {
+		assert.Contains(t, scanner.Text(), "container_cpu,cgroup_version=2 usage=0.5")
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, 2, lines)
+}
+
+func TestWriterRedial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	w, err := Dial("tcp", ln.Addr().String(), &InfluxEncoder{})
+	require.NoError(t, err)
+	defer w.Close()
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// The peer is gone: a write fails, once the kernel has noticed it.
+	require.Eventually(t, func() bool {
+		return w.Write(testInfo, testSample) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, w.Write(testInfo, testSample), "the next write dials again")
+
+	conn, err = ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Contains(t, line, "container_cpu,cgroup_version=2 usage=0.5")
+}
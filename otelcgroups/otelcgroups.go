@@ -0,0 +1,103 @@
+// Package otelcgroups registers the container CPU statistics of a
+// cgroups.Collector as OpenTelemetry asynchronous instruments.
+package otelcgroups
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+// Source provides the samples and cgroup information observed by the
+// instruments. It is implemented by *cgroups.Collector.
+type Source interface {
+	Latest() (cgroups.Sample, bool)
+	Info() cgroups.Info
+}
+
+// Register creates the container CPU instruments on meter, observed from
+// the latest sample of c, with attrs added to every observation. Nothing
+// is observed until c has collected a sample. Unregister the returned
+// registration to stop the observations.
+//
+// The instruments are:
+//
+//	container.cpu.time                          counter of the cgroup CPU time, in seconds
+//	container.cpu.usage                         cores used during the last interval
+//	container.cpu.utilization                   usage as a fraction of the limit
+//	container.cpu.limit                         cores available to the container
+//	container.cpu.throttling.periods            counter of enforcement periods
+//	container.cpu.throttling.throttled_periods  counter of throttled periods
+//	container.cpu.throttling.time               counter of the throttled time, in seconds
+func Register(meter metric.Meter, c Source, attrs ...attribute.KeyValue) (metric.Registration, error) {
+	cpuTime, err := meter.Float64ObservableCounter("container.cpu.time",
+		metric.WithDescription("Total CPU time consumed by the container."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := meter.Float64ObservableGauge("container.cpu.usage",
+		metric.WithDescription("CPU cores used by the container during the last sampling interval."),
+		metric.WithUnit("{cpu}"))
+	if err != nil {
+		return nil, err
+	}
+
+	utilization, err := meter.Float64ObservableGauge("container.cpu.utilization",
+		metric.WithDescription("CPU usage of the container relative to its limit."),
+		metric.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := meter.Float64ObservableGauge("container.cpu.limit",
+		metric.WithDescription("CPU cores available to the container."),
+		metric.WithUnit("{cpu}"))
+	if err != nil {
+		return nil, err
+	}
+
+	periods, err := meter.Int64ObservableCounter("container.cpu.throttling.periods",
+		metric.WithDescription("Number of CFS enforcement periods elapsed."),
+		metric.WithUnit("{period}"))
+	if err != nil {
+		return nil, err
+	}
+
+	throttledPeriods, err := meter.Int64ObservableCounter("container.cpu.throttling.throttled_periods",
+		metric.WithDescription("Number of CFS enforcement periods the container was throttled in."),
+		metric.WithUnit("{period}"))
+	if err != nil {
+		return nil, err
+	}
+
+	throttledTime, err := meter.Float64ObservableCounter("container.cpu.throttling.time",
+		metric.WithDescription("Total time the container was throttled."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	opt := metric.WithAttributes(attrs...)
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s, ok := c.Latest()
+		if !ok {
+			return nil
+		}
+
+		o.ObserveFloat64(cpuTime, s.CPUTime.Seconds(), opt)
+		o.ObserveFloat64(usage, s.Usage, opt)
+		o.ObserveFloat64(utilization, s.Percent/100, opt)
+		o.ObserveFloat64(limit, c.Info().Limit, opt)
+		o.ObserveInt64(periods, int64(s.Throttling.Periods), opt)
+		o.ObserveInt64(throttledPeriods, int64(s.Throttling.ThrottledPeriods), opt)
+		o.ObserveFloat64(throttledTime, s.Throttling.ThrottledTime.Seconds(), opt)
+
+		return nil
+	}, cpuTime, usage, utilization, limit, periods, throttledPeriods, throttledTime)
+}
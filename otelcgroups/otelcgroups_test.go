@@ -0,0 +1,140 @@
+package otelcgroups
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+// fakeSource serves a fixed sample.
+type fakeSource struct {
+	sample cgroups.Sample
+	ok     bool
+}
+
+func (f *fakeSource) Latest() (cgroups.Sample, bool) {
+	return f.sample, f.ok
+}
+
+func (f *fakeSource) Info() cgroups.Info {
+	return cgroups.Info{Version: 2, Limit: 1.5}
+}
+
+func TestRegister(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	src := &fakeSource{}
+	attrs := attribute.NewSet(attribute.String("container.id", "abc"))
+	reg, err := Register(provider.Meter("test"), src, attrs.ToSlice()...)
+	require.NoError(t, err)
+	defer reg.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	assert.Empty(t, rm.ScopeMetrics, "no sample collected yet")
+
+	src.sample = cgroups.Sample{
+		CPUTime: 90 * time.Second,
+		Usage:   0.75,
+		Percent: 50,
+		Throttling: cgroups.Throttling{
+			Periods:          100,
+			ThrottledPeriods: 20,
+			ThrottledTime:    1500 * time.Millisecond,
+		},
+	}
+	src.ok = true
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	sum := func(name, unit string, value float64) metricdata.Metrics {
+		return metricdata.Metrics{Name: name, Unit: unit, Data: metricdata.Sum[float64]{
+			DataPoints:  []metricdata.DataPoint[float64]{{Attributes: attrs, Value: value}},
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+		}}
+	}
+	intSum := func(name string, value int64) metricdata.Metrics {
+		return metricdata.Metrics{Name: name, Unit: "{period}", Data: metricdata.Sum[int64]{
+			DataPoints:  []metricdata.DataPoint[int64]{{Attributes: attrs, Value: value}},
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+		}}
+	}
+	gauge := func(name, unit string, value float64) metricdata.Metrics {
+		return metricdata.Metrics{Name: name, Unit: unit, Data: metricdata.Gauge[float64]{
+			DataPoints: []metricdata.DataPoint[float64]{{Attributes: attrs, Value: value}},
+		}}
+	}
+
+	expected := map[string]metricdata.Metrics{
+		"container.cpu.time":                         sum("container.cpu.time", "s", 90),
+		"container.cpu.usage":                        gauge("container.cpu.usage", "{cpu}", 0.75),
+		"container.cpu.utilization":                  gauge("container.cpu.utilization", "1", 0.5),
+		"container.cpu.limit":                        gauge("container.cpu.limit", "{cpu}", 1.5),
+		"container.cpu.throttling.periods":           intSum("container.cpu.throttling.periods", 100),
+		"container.cpu.throttling.throttled_periods": intSum("container.cpu.throttling.throttled_periods", 20),
+		"container.cpu.throttling.time":              sum("container.cpu.throttling.time", "s", 1.5),
+	}
+
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, len(expected))
+	for _, m := range metrics {
+		e, ok := expected[m.Name]
+		require.True(t, ok, m.Name)
+		assert.NotEmpty(t, m.Description, m.Name)
+		m.Description = ""
+		metricdatatest.AssertEqual(t, e, m, metricdatatest.IgnoreTimestamp())
+	}
+}
+
+func TestRegisterCollector(t *testing.T) {
+	c, err := cgroups.NewCollector()
+	if err != nil {
+		t.Skipf("cgroups not available: %v", err)
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	reg, err := Register(provider.Meter("test"), c, attribute.String("container.id", "abc"))
+	require.NoError(t, err)
+	defer reg.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	assert.Empty(t, rm.ScopeMetrics, "no sample collected yet")
+
+	_, err = c.Collect()
+	require.NoError(t, err)
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	names := make(map[string]bool)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		names[m.Name] = true
+	}
+	for _, name := range []string{
+		"container.cpu.time",
+		"container.cpu.usage",
+		"container.cpu.utilization",
+		"container.cpu.limit",
+		"container.cpu.throttling.periods",
+		"container.cpu.throttling.throttled_periods",
+		"container.cpu.throttling.time",
+	} {
+		assert.True(t, names[name], name)
+	}
+}
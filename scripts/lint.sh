@@ -26,6 +26,7 @@ echo "Checking gofmt"
 gofmt -d -s "$GO_FILES" 2>&1 | tee lint.log
 echo "Checking go vet"
 go vet ./... 2>&1 | tee -a lint.log
+(cd otelcgroups && go vet ./...) 2>&1 | tee -a lint.log
 echo "Checking golint"
 "$(pwd)"/bin/golangci-lint run | tee -a lint.log
 echo "Checking staticcheck"
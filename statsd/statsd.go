@@ -0,0 +1,147 @@
+// Package statsd pushes the container CPU statistics of a cgroups.Collector
+// as StatsD gauges over UDP, with optional DogStatsD tags.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+const defaultInterval = 10 * time.Second
+
+// Source provides the samples to push. *cgroups.Collector implements it.
+type Source interface {
+	Latest() (cgroups.Sample, bool)
+	Info() cgroups.Info
+}
+
+// Config configures a Pusher.
+type Config struct {
+	// Addr is the `host:port` of the StatsD server.
+	Addr string
+	// Prefix is prepended to every metric name, e.g. `myapp.`.
+	Prefix string
+	// Tags are DogStatsD tags in the `key:value` format added to every
+	// metric. Plain StatsD servers do not support tags; leave it empty.
+	Tags []string
+	// Interval is the push interval used by Run. Defaults to 10s.
+	Interval time.Duration
+}
+
+// A Pusher sends the latest sample of a Source as gauges:
+//
+//	cpu.usage            cores used during the last interval
+//	cpu.percent          usage in percent of the limit
+//	cpu.limit            cores available to the container
+//	cpu.throttled_ratio  ratio of throttled periods since the last push
+//	cpu.throttled_time   seconds throttled since the last push
+type Pusher struct {
+	src  Source
+	cfg  Config
+	conn net.Conn
+
+	mu   sync.Mutex
+	prev *cgroups.Throttling
+}
+
+// New returns a Pusher sending the samples of src to cfg.Addr.
+func New(src Source, cfg Config) (*Pusher, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("statsd: empty address")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pusher{
+		src:  src,
+		cfg:  cfg,
+		conn: conn,
+	}, nil
+}
+
+// Tags returns the DogStatsD tags describing the cgroup of info: its
+// version and, when found in the path, the container ID and pod uid.
+func Tags(info cgroups.Info) []string {
+	tags := []string{"cgroup_version:" + strconv.Itoa(info.Version)}
+	if id := info.ContainerID(); id != "" {
+		tags = append(tags, "container_id:"+id)
+	}
+	if uid := info.PodUID(); uid != "" {
+		tags = append(tags, "pod_uid:"+uid)
+	}
+	return tags
+}
+
+// Push sends the latest sample in a single packet. It does nothing if no
+// sample was collected yet.
+func (p *Pusher) Push() error {
+	s, ok := p.src.Latest()
+	if !ok {
+		return nil
+	}
+
+	p.mu.Lock()
+	var delta cgroups.Throttling
+	if p.prev != nil {
+		delta = s.Throttling.Sub(*p.prev)
+	}
+	p.prev = &s.Throttling
+	p.mu.Unlock()
+
+	var b strings.Builder
+	p.gauge(&b, "cpu.usage", s.Usage)
+	p.gauge(&b, "cpu.percent", s.Percent)
+	p.gauge(&b, "cpu.limit", p.src.Info().Limit)
+	p.gauge(&b, "cpu.throttled_ratio", delta.Ratio())
+	p.gauge(&b, "cpu.throttled_time", delta.ThrottledTime.Seconds())
+
+	_, err := p.conn.Write([]byte(strings.TrimSuffix(b.String(), "\n")))
+	return err
+}
+
+// Run pushes a sample every interval until ctx is done.
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = p.Push()
+		}
+	}
+}
+
+// Close closes the connection.
+func (p *Pusher) Close() error {
+	return p.conn.Close()
+}
+
+// gauge appends a line in the format `<prefix><name>:<value>|g|#<tags>`.
+func (p *Pusher) gauge(b *strings.Builder, name string, value float64) {
+	b.WriteString(p.cfg.Prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteString("|g")
+	if len(p.cfg.Tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(p.cfg.Tags, ","))
+	}
+	b.WriteByte('\n')
+}
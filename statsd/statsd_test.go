@@ -0,0 +1,103 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minhnguyen98/container-cpu-usage/cgroups"
+)
+
+type fakeSource struct {
+	sample cgroups.Sample
+	ok     bool
+}
+
+func (f *fakeSource) Latest() (cgroups.Sample, bool) {
+	return f.sample, f.ok
+}
+
+func (f *fakeSource) Info() cgroups.Info {
+	return cgroups.Info{Version: 2, Limit: 2}
+}
+
+func TestPusher(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	src := &fakeSource{}
+	p, err := New(src, Config{
+		Addr:   conn.LocalAddr().String(),
+		Prefix: "app.",
+		Tags:   []string{"env:test", "cgroup_version:2"},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Push(), "no sample yet")
+
+	src.sample = cgroups.Sample{
+		Usage:      0.5,
+		Percent:    25,
+		Throttled:  250 * time.Millisecond,
+		Throttling: cgroups.Throttling{Periods: 10, ThrottledPeriods: 2, ThrottledTime: time.Second},
+	}
+	src.ok = true
+	require.NoError(t, p.Push())
+
+	src.sample.Throttling = cgroups.Throttling{Periods: 20, ThrottledPeriods: 7, ThrottledTime: 1750 * time.Millisecond}
+	require.NoError(t, p.Push())
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, `app.cpu.usage:0.5|g|#env:test,cgroup_version:2
+app.cpu.percent:25|g|#env:test,cgroup_version:2
+app.cpu.limit:2|g|#env:test,cgroup_version:2
+app.cpu.throttled_ratio:0|g|#env:test,cgroup_version:2
+app.cpu.throttled_time:0|g|#env:test,cgroup_version:2`, string(buf[:n]))
+
+	n, _, err = conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf[:n]), "app.cpu.throttled_ratio:0.5|g")
+	assert.Contains(t, string(buf[:n]), "app.cpu.throttled_time:0.75|g", "throttled time since the last push, not of the last sample")
+}
+
+func TestTags(t *testing.T) {
+	id := "1753b7cbbf62734d812936961224d5bc0cf8f45214e0d5cdd1a781a053e7c48f"
+
+	tests := []struct {
+		info     cgroups.Info
+		expected []string
+	}{
+		{cgroups.Info{Version: 1, Path: "/sys/fs/cgroup/cpu"}, []string{"cgroup_version:1"}},
+		{cgroups.Info{Version: 2, Path: "/sys/fs/cgroup/system.slice/docker-" + id + ".scope"}, []string{
+			"cgroup_version:2",
+			"container_id:" + id,
+		}},
+		{cgroups.Info{Version: 1, Path: "/sys/fs/cgroup/cpu,cpuacct", CGroup: "/docker/" + id}, []string{
+			"cgroup_version:1",
+			"container_id:" + id,
+		}},
+		{cgroups.Info{Version: 2, Path: "/sys/fs/cgroup", CGroup: "/kubepods/burstable/pod1234-abcd/" + id}, []string{
+			"cgroup_version:2",
+			"container_id:" + id,
+			"pod_uid:1234-abcd",
+		}},
+		{cgroups.Info{Version: 2, Path: "/sys/fs/cgroup/kubepods/burstable/pod1234-abcd/" + id}, []string{
+			"cgroup_version:2",
+			"container_id:" + id,
+			"pod_uid:1234-abcd",
+		}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Tags(tt.info), tt.info.Path)
+	}
+}